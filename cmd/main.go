@@ -1,37 +1,44 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"havocai-assignment/config"
-	"havocai-assignment/parser"
+	"havocai-assignment/converter"
 	"havocai-assignment/pkg/cmdutil"
 	"havocai-assignment/pkg/fileutil"
 	"os"
 )
 
 func main() {
-	xmlPath, configPath, outputPath := cmdutil.ValidateFlags()
-
-	config, err := config.LoadFile(configPath)
-	if err != nil {
-		cmdutil.FatalError("error loading config file: %+v\n", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
 	}
 
-	input, err := os.ReadFile(xmlPath)
+	flags := cmdutil.ValidateFlags()
+
+	config, err := config.LoadFile(flags.ConfigPath, config.WithOverlays(flags.Overlays...), config.WithParams(flags.Params))
 	if err != nil {
-		cmdutil.FatalError("error reading xml input file: %+v\n", err)
+		cmdutil.FatalError("error loading config file: %+v\n", err)
 	}
 
-	xmlPatients, err := parser.ParseXML(input)
-	if err != nil {
-		cmdutil.FatalError("error parsing XML: %+v\n", err)
+	var opts []converter.Option
+	if flags.Now != "" {
+		now, err := converter.ParseTime(flags.Now)
+		if err != nil {
+			cmdutil.FatalError("error parsing -now flag: %+v\n", err)
+		}
+		opts = append(opts, converter.WithClock(converter.FixedClock(now)))
 	}
 
-	jsonPatients, err := parser.ConvertToJSON(xmlPatients, config)
+	input, err := os.Open(flags.XMLPath)
 	if err != nil {
-		cmdutil.FatalError("error converting to JSON: %+v\n", err)
+		cmdutil.FatalError("error reading xml input file: %+v\n", err)
 	}
+	defer input.Close()
 
+	outputPath := flags.OutputPath
 	if outputPath == "" {
 		outputPath, err = fileutil.GetOutputPath()
 		if err != nil {
@@ -39,10 +46,47 @@ func main() {
 		}
 	}
 
-	err = fileutil.WriteToFile(outputPath, jsonPatients)
+	output, err := fileutil.CreateFile(outputPath)
+	if err != nil {
+		cmdutil.FatalError("error writing output to file: %+v\n", err)
+	}
+
+	conv, err := converter.New(config, opts...)
+	if err != nil {
+		cmdutil.FatalError("error creating converter: %+v\n", err)
+	}
+
+	err = conv.Convert(context.Background(), input, output)
+	if err != nil {
+		output.Close()
+		os.Remove(outputPath)
+		cmdutil.FatalError("error converting to JSON: %+v\n", err)
+	}
+
+	err = output.Close()
 	if err != nil {
 		cmdutil.FatalError("error writing output to file: %+v\n", err)
 	}
 
 	fmt.Printf("Successfully converted XML data to JSON. Output written to: %v\n", outputPath)
 }
+
+// migrate rewrites a config written for an older version of the config schema to the current version.
+func migrate(args []string) {
+	flags := cmdutil.ValidateMigrateFlags(args)
+
+	migrated, err := config.Migrate(flags.ConfigPath)
+	if err != nil {
+		cmdutil.FatalError("error migrating config file: %+v\n", err)
+	}
+
+	if flags.OutputPath == "" {
+		os.Stdout.Write(migrated)
+		return
+	}
+	err = os.WriteFile(flags.OutputPath, migrated, 0644)
+	if err != nil {
+		cmdutil.FatalError("error writing migrated config file: %+v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "Migrated config written to: %v\n", flags.OutputPath)
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"fmt"
+	"havocai-assignment/internal/maputil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Option configures how a config is loaded.
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	overlays []string
+	params   map[string]string
+}
+
+// WithOverlays merges the given config files on top of the loaded config, in order, so that an
+// environment can override individual fields of a shared config.
+func WithOverlays(paths ...string) Option {
+	return func(o *loadOptions) {
+		o.overlays = append(o.overlays, paths...)
+	}
+}
+
+// loader reads config files along with the files they include.
+type loader struct {
+	// files that are being loaded, used to detect include cycles
+	stack        []string
+	interpolator interpolator
+	// placeholders records by JSON path the values of the merged files that were a single
+	// placeholder, see interpolate
+	placeholders map[string]bool
+	// migrated is set when any of the files was written for an older version of the schema
+	migrated bool
+}
+
+// load reads a config file and merges it on top of the files it includes. The file is migrated
+// to the current version and its placeholders are replaced first, then relative paths to
+// included files and lookup tables are resolved against the directory of the file they are in.
+func (l *loader) load(filePath string) (map[string]interface{}, error) {
+	filePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for i, loading := range l.stack {
+		if loading == filePath {
+			cycle := append(append([]string{}, l.stack[i:]...), filePath)
+			return nil, fmt.Errorf("include cycle: %v", strings.Join(cycle, " -> "))
+		}
+	}
+	l.stack = append(l.stack, filePath)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	values, err := decode(data, detectFormat(filePath, data))
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %v: %w", filePath, err)
+	}
+	if values == nil {
+		return nil, fmt.Errorf("config file %v is empty", filePath)
+	}
+	migrated, err := migrate(values)
+	if err != nil {
+		return nil, fmt.Errorf("config file %v: %w", filePath, err)
+	}
+	l.migrated = l.migrated || migrated
+	placeholders := make(map[string]bool)
+	if err := l.interpolator.interpolate(values, placeholders); err != nil {
+		return nil, fmt.Errorf("config file %v: %w", filePath, err)
+	}
+
+	dir := filepath.Dir(filePath)
+	resolveTablePaths(values, dir)
+
+	includes, err := stringList(values["include"])
+	if err != nil {
+		return nil, fmt.Errorf("config file %v: include %w", filePath, err)
+	}
+	delete(values, "include")
+
+	merged := map[string]interface{}{}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(dir, include)
+		}
+		included, err := l.load(include)
+		if err != nil {
+			return nil, err
+		}
+		merged = merge(merged, included)
+	}
+	// the values of the file are merged on top of those of the files it includes, and so are
+	// its placeholders
+	for path, whole := range placeholders {
+		l.placeholders[path] = whole
+	}
+	return merge(merged, values), nil
+}
+
+// merge merges src on top of dst and returns the result. Objects are merged key by key, any
+// other value in src replaces the value in dst, including lists, and a null in src removes the
+// key from dst. Neither map is modified.
+func merge(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(dst)+len(src))
+	for key, val := range dst {
+		merged[key] = val
+	}
+
+	for key, val := range src {
+		if val == nil {
+			delete(merged, key)
+			continue
+		}
+		srcMap, srcIsMap := val.(map[string]interface{})
+		dstMap, dstIsMap := merged[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			merged[key] = merge(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			// nulls within a new object still remove keys, so an object never holds a null
+			merged[key] = merge(nil, srcMap)
+			continue
+		}
+		merged[key] = val
+	}
+	return merged
+}
+
+// resolveTablePaths makes every relative table_file absolute, so that a lookup table is found
+// next to the file that refers to it even when that file is included from somewhere else.
+func resolveTablePaths(value interface{}, dir string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if file, ok := item.(string); ok && key == "table_file" && !filepath.IsAbs(file) {
+				v[key] = filepath.Join(dir, file)
+				continue
+			}
+			resolveTablePaths(item, dir)
+		}
+	case []interface{}:
+		for _, item := range v {
+			resolveTablePaths(item, dir)
+		}
+	}
+}
+
+// resolveDefinitions replaces every transformation written as {"use": "name", ...} with the
+// transformation in definitions with that name, merged with the other keys of the transformation.
+// Definitions can use other definitions.
+func resolveDefinitions(values map[string]interface{}) error {
+	definitions, _ := values["definitions"].(map[string]interface{})
+	delete(values, "definitions")
+
+	transformations, ok := values["transformations"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	// resolve in a fixed order so that the same error is reported every time
+	for _, jsonField := range maputil.SortedKeys(transformations) {
+		transformation, ok := transformations[jsonField].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		resolved, err := resolveUse(transformation, definitions, nil)
+		if err != nil {
+			return fmt.Errorf("transformations.%v: %w", jsonField, err)
+		}
+		transformations[jsonField] = resolved
+	}
+	return nil
+}
+
+func resolveUse(transformation map[string]interface{}, definitions map[string]interface{}, using []string) (map[string]interface{}, error) {
+	if steps, ok := transformation["steps"].([]interface{}); ok {
+		resolvedSteps := make([]interface{}, len(steps))
+		for i, step := range steps {
+			stepMap, ok := step.(map[string]interface{})
+			if !ok {
+				resolvedSteps[i] = step
+				continue
+			}
+			resolved, err := resolveUse(stepMap, definitions, using)
+			if err != nil {
+				return nil, fmt.Errorf("steps[%d]: %w", i, err)
+			}
+			resolvedSteps[i] = resolved
+		}
+		transformation = merge(transformation, map[string]interface{}{"steps": resolvedSteps})
+	}
+
+	use, ok := transformation["use"]
+	if !ok {
+		return transformation, nil
+	}
+	name, ok := use.(string)
+	if !ok {
+		return nil, fmt.Errorf("use must be the name of a definition")
+	}
+	using = append(append([]string{}, using...), name)
+	for i, usedName := range using[:len(using)-1] {
+		if usedName == name {
+			return nil, fmt.Errorf("definition cycle: %v", strings.Join(using[i:], " -> "))
+		}
+	}
+	definition, ok := definitions[name].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unknown definition %q", name)
+	}
+
+	base, err := resolveUse(definition, definitions, using)
+	if err != nil {
+		return nil, err
+	}
+	overrides := merge(transformation, map[string]interface{}{"use": nil})
+	return merge(base, overrides), nil
+}
+
+// stringList reads a value that is either a single string or a list of strings.
+func stringList(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a path or a list of paths")
+			}
+			list[i] = str
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("must be a path or a list of paths")
+	}
+}
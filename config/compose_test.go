@@ -0,0 +1,226 @@
+package config
+
+import (
+	"havocai-assignment/internal/maputil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst      map[string]interface{}
+		src      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:     "new keys are added",
+			dst:      map[string]interface{}{"root": "patients"},
+			src:      map[string]interface{}{"record_path": "Patients/Patient"},
+			expected: map[string]interface{}{"root": "patients", "record_path": "Patients/Patient"},
+		},
+		{
+			name:     "values are replaced",
+			dst:      map[string]interface{}{"root": "patients"},
+			src:      map[string]interface{}{"root": "people"},
+			expected: map[string]interface{}{"root": "people"},
+		},
+		{
+			name: "objects are merged",
+			dst: map[string]interface{}{
+				"extras": map[string]interface{}{"operation": "time_difference", "unit": "years"},
+			},
+			src: map[string]interface{}{
+				"extras": map[string]interface{}{"unit": "months"},
+			},
+			expected: map[string]interface{}{
+				"extras": map[string]interface{}{"operation": "time_difference", "unit": "months"},
+			},
+		},
+		{
+			name:     "lists are replaced",
+			dst:      map[string]interface{}{"fields": []interface{}{"FirstName", "LastName"}},
+			src:      map[string]interface{}{"fields": []interface{}{"LastName"}},
+			expected: map[string]interface{}{"fields": []interface{}{"LastName"}},
+		},
+		{
+			name:     "null removes a key",
+			dst:      map[string]interface{}{"root": "patients", "record_path": "Patients/Patient"},
+			src:      map[string]interface{}{"record_path": nil},
+			expected: map[string]interface{}{"root": "patients"},
+		},
+		{
+			name:     "null within a new object is removed",
+			dst:      map[string]interface{}{},
+			src:      map[string]interface{}{"extras": map[string]interface{}{"unit": "years", "format": nil}},
+			expected: map[string]interface{}{"extras": map[string]interface{}{"unit": "years"}},
+		},
+		{
+			name:     "object replaces a value",
+			dst:      map[string]interface{}{"ID": "id"},
+			src:      map[string]interface{}{"ID": map[string]interface{}{"field": "id", "required": true}},
+			expected: map[string]interface{}{"ID": map[string]interface{}{"field": "id", "required": true}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := merge(nil, test.dst)
+			require.Equal(t, test.expected, merge(test.dst, test.src))
+			require.Equal(t, dst, test.dst, "merge should not modify dst")
+		})
+	}
+}
+
+func TestResolveDefinitions(t *testing.T) {
+	values := map[string]interface{}{
+		"definitions": map[string]interface{}{
+			"age_in_years": map[string]interface{}{
+				"type": "calculate",
+				"params": map[string]interface{}{
+					"fields": []interface{}{"DateOfBirth", "CurrentTime"},
+					"extras": map[string]interface{}{"operation": "time_difference", "unit": "years"},
+				},
+			},
+			"age_in_months": map[string]interface{}{
+				"use":    "age_in_years",
+				"params": map[string]interface{}{"extras": map[string]interface{}{"unit": "months"}},
+			},
+			"trim": map[string]interface{}{
+				"type":   "string",
+				"params": map[string]interface{}{"fields": []interface{}{"$"}, "extras": map[string]interface{}{"operation": "trim"}},
+			},
+		},
+		"transformations": map[string]interface{}{
+			"age": map[string]interface{}{"use": "age_in_months", "required": true},
+			"mrn": map[string]interface{}{
+				"steps": []interface{}{
+					map[string]interface{}{"type": "string", "params": map[string]interface{}{"fields": []interface{}{"MRN"}, "extras": map[string]interface{}{"operation": "upper"}}},
+					map[string]interface{}{"use": "trim"},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, resolveDefinitions(values))
+	require.Equal(t, map[string]interface{}{
+		"transformations": map[string]interface{}{
+			"age": map[string]interface{}{
+				"type": "calculate",
+				"params": map[string]interface{}{
+					"fields": []interface{}{"DateOfBirth", "CurrentTime"},
+					"extras": map[string]interface{}{"operation": "time_difference", "unit": "months"},
+				},
+				"required": true,
+			},
+			"mrn": map[string]interface{}{
+				"steps": []interface{}{
+					map[string]interface{}{"type": "string", "params": map[string]interface{}{"fields": []interface{}{"MRN"}, "extras": map[string]interface{}{"operation": "upper"}}},
+					map[string]interface{}{"type": "string", "params": map[string]interface{}{"fields": []interface{}{"$"}, "extras": map[string]interface{}{"operation": "trim"}}},
+				},
+			},
+		},
+	}, values)
+}
+
+func TestResolveDefinitionsErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name: "unknown definition",
+			values: map[string]interface{}{
+				"transformations": map[string]interface{}{
+					"age": map[string]interface{}{"use": "age_in_yaers"},
+				},
+			},
+			expectedErr: `transformations.age: unknown definition "age_in_yaers"`,
+		},
+		{
+			name: "definition cycle",
+			values: map[string]interface{}{
+				"definitions": map[string]interface{}{
+					"a": map[string]interface{}{"use": "b"},
+					"b": map[string]interface{}{"use": "a"},
+				},
+				"transformations": map[string]interface{}{
+					"age": map[string]interface{}{"use": "a"},
+				},
+			},
+			expectedErr: "transformations.age: definition cycle: a -> b -> a",
+		},
+		{
+			name: "use is not a name",
+			values: map[string]interface{}{
+				"transformations": map[string]interface{}{
+					"age": map[string]interface{}{"use": []interface{}{"a"}},
+				},
+			},
+			expectedErr: "transformations.age: use must be the name of a definition",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.EqualError(t, resolveDefinitions(test.values), test.expectedErr)
+		})
+	}
+}
+
+func TestLoadFileComposition(t *testing.T) {
+	cfg, err := LoadFile("../test/testdata/composition/config.yaml")
+	require.NoError(t, err)
+
+	require.Equal(t, "patients", cfg.RootName)
+	require.Len(t, cfg.Mappings, 1)
+	require.Equal(t, "id", cfg.Mappings["@ID"].Field)
+	require.Equal(t, []string{"age", "gender", "name"}, maputil.SortedKeys(cfg.Transformations))
+	require.Equal(t, "concat", cfg.Transformations["name"].Type)
+	require.Equal(t, "years", cfg.Transformations["age"].Params.Extras["unit"])
+
+	// the table file of the included lookup config is found next to that config
+	gender := cfg.Transformations["gender"]
+	require.Equal(t, "U", gender.Params.Extras["default"])
+	require.Equal(t, "Male", gender.Params.Extras["table"].(map[string]interface{})["M"])
+
+	cfg, err = LoadFile("../test/testdata/composition/config.yaml", WithOverlays("../test/testdata/composition/overlay_months.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "months", cfg.Transformations["age"].Params.Extras["unit"])
+	require.Equal(t, "time_difference", cfg.Transformations["age"].Params.Extras["operation"])
+}
+
+func TestLoadFileIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"include": "a.yaml", "root": "patients"}`,
+		"a.yaml":      "include: [b.toml]\n",
+		"b.toml":      "include = [\"a.yaml\"]\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	_, err := LoadFile(filepath.Join(dir, "config.json"))
+	require.EqualError(t, err, "include cycle: "+filepath.Join(dir, "a.yaml")+" -> "+filepath.Join(dir, "b.toml")+" -> "+filepath.Join(dir, "a.yaml"))
+}
+
+func TestLoadFileIncludedTwice(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{"include": ["a.yaml", "b.yaml"]}`,
+		"a.yaml":      "include: shared.yaml\nmappings:\n  ID: id\n",
+		"b.yaml":      "include: shared.yaml\nmappings:\n  Name: name\n",
+		"shared.yaml": "root: patients\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	cfg, err := LoadFile(filepath.Join(dir, "config.json"))
+	require.NoError(t, err)
+	require.Equal(t, "patients", cfg.RootName)
+	require.Len(t, cfg.Mappings, 2)
+}
@@ -3,16 +3,72 @@ package config
 import (
 	"encoding/json"
 	"havocai-assignment/models"
-	"os"
+	"path/filepath"
+
+	// the built-in transformation types register the schemas that configs are validated against
+	_ "havocai-assignment/parser"
 )
 
-func LoadFile(filePath string) (*models.Config, error) {
-	data, err := os.ReadFile(filePath)
+// LoadFile reads a config from a JSON, YAML or TOML file. The format is chosen by the file's
+// extension, or from its content when the extension is not .json, .yaml, .yml or .toml.
+// The files listed in the config's include are merged underneath it, any overlays given with
+// WithOverlays are merged on top of it, and transformations that use a definition are filled
+// in from it. Placeholders such as ${NAME} in string values are replaced with the params given
+// with WithParams or with environment variables. The config is validated before it is returned.
+func LoadFile(filePath string, opts ...Option) (*models.Config, error) {
+	var options loadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	l := &loader{interpolator: interpolator{params: options.params}, placeholders: make(map[string]bool)}
+	values, err := l.load(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range options.overlays {
+		overlayValues, err := l.load(overlay)
+		if err != nil {
+			return nil, err
+		}
+		values = merge(values, overlayValues)
+	}
+	if l.migrated {
+		setDefaultRoot(values)
+	}
+	// definitions are converted before they are used, and transformations that get their type
+	// from a definition once it has been used
+	convertTypes(values, l.placeholders)
+	if err := resolveDefinitions(values); err != nil {
+		return nil, err
+	}
+	convertTypes(values, l.placeholders)
+
+	config, err := toConfig(values)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	err = loadLookupTables(config, filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// toConfig decodes generic JSON values into a Config.
+func toConfig(values map[string]interface{}) (*models.Config, error) {
+	data, err := json.Marshal(values)
 	if err != nil {
 		return nil, err
 	}
 
 	var config *models.Config
-	err = json.Unmarshal(data, &config)
-	return config, err
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"havocai-assignment/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFileLookupTables(t *testing.T) {
+	cfg, err := LoadFile("../test/testdata/lookup/config.json")
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"M": "Male",
+		"F": "Female",
+		"U": "Unknown",
+	}, cfg.Transformations["gender"].Params.Extras["table"])
+
+	require.Equal(t, map[string]interface{}{
+		"100": "Providence General",
+		"200": "Muskegon Memorial",
+	}, cfg.Transformations["facility"].Params.Extras["table"])
+}
+
+func TestLoadFileLookupTablesInBranches(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gender.csv"), []byte("code,display\nM,Male\nF,Female\n"), 0644))
+	configPath := filepath.Join(dir, "config.json")
+	config := `{
+		"root": "patients",
+		"transformations": {
+			"gender": {
+				"type": "if",
+				"params": {
+					"extras": {
+						"condition": {"exists": "Gender"},
+						"then": {"transformation": {"type": "lookup", "params": {"fields": ["Gender"], "extras": {"table_file": "gender.csv"}}}},
+						"else": "Unknown"
+					}
+				}
+			}
+		}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+
+	cfg, err := LoadFile(configPath)
+	require.NoError(t, err)
+
+	then := cfg.Transformations["gender"].Params.Extras["then"].(map[string]interface{})
+	nested := then["transformation"].(map[string]interface{})
+	extras := nested["params"].(map[string]interface{})["extras"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"M": "Male", "F": "Female"}, extras["table"])
+
+	// a missing table file within a branch is reported when the config is loaded
+	config = strings.Replace(config, "gender.csv", "missing.csv", 1)
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+	_, err = LoadFile(configPath)
+	require.ErrorContains(t, err, "transformation gender: then: error reading lookup table")
+}
+
+func TestLoadFileLookupTableErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		files  map[string]string
+	}{
+		{
+			name:   "missing table file",
+			config: `{"root": "patients", "transformations": {"gender": {"type": "lookup", "params": {"fields": ["Gender"], "extras": {"table_file": "missing.csv"}}}}}`,
+		},
+		{
+			name:   "unsupported table file",
+			config: `{"root": "patients", "transformations": {"gender": {"type": "lookup", "params": {"fields": ["Gender"], "extras": {"table_file": "gender.txt"}}}}}`,
+			files:  map[string]string{"gender.txt": "M=Male"},
+		},
+		{
+			name:   "csv with wrong number of columns",
+			config: `{"root": "patients", "transformations": {"gender": {"type": "lookup", "params": {"fields": ["Gender"], "extras": {"table_file": "gender.csv"}}}}}`,
+			files:  map[string]string{"gender.csv": "code,display\nM,Male,extra\n"},
+		},
+		{
+			name:   "table file within steps",
+			config: `{"root": "patients", "transformations": {"gender": {"steps": [{"type": "lookup", "params": {"fields": ["$"], "extras": {"table_file": "missing.json"}}}]}}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+			}
+			configPath := filepath.Join(dir, "config.json")
+			require.NoError(t, os.WriteFile(configPath, []byte(test.config), 0644))
+
+			_, err := LoadFile(configPath)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadFileValidates(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+		"mappings": {"ID": "id"},
+		"transformations": {
+			"age": {
+				"type": "calculate",
+				"params": {
+					"fields": ["DateOfBirth", "CurrentTime"],
+					"extras": {"operation": "time_diference", "decimal_precision": "2"}
+				}
+			},
+			"name": {"type": "concatenate", "params": {"fields": ["FirstName"]}}
+		}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+
+	_, err := LoadFile(configPath)
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, []models.Problem{
+		{Path: "root", Message: "is required"},
+		{Path: "transformations.age.params.extras.decimal_precision", Message: "must be a whole number"},
+		{Path: "transformations.age.params.extras.operation", Message: `unsupported value "time_diference", must be one of: add, subtract, multiply, divide, modulo, time_difference`},
+		{Path: "transformations.name.type", Message: `unknown transformation type "concatenate"`},
+	}, validationErr.Problems)
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	expected, err := LoadFile("../test/testdata/basicpatient/config.json")
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		"../test/testdata/basicpatient/config.yaml",
+		"../test/testdata/basicpatient/config.toml",
+	} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			actual, err := LoadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+
+	// a config without a known extension is read in the format of its content
+	data, err := os.ReadFile("../test/testdata/basicpatient/config.yaml")
+	require.NoError(t, err)
+	configPath := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(configPath, data, 0644))
+
+	actual, err := LoadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// format is the file format of a config.
+type format string
+
+const (
+	formatJSON format = "json"
+	formatYAML format = "yaml"
+	formatTOML format = "toml"
+)
+
+// detectFormat returns the format of a config file from its extension, or from its content when
+// the extension is not one of .json, .yaml, .yml or .toml.
+func detectFormat(filePath string, data []byte) format {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return formatJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	default:
+		return sniffFormat(data)
+	}
+}
+
+var (
+	// tomlTable matches a TOML table header such as [transformations.age] or [[steps]]
+	tomlTable = regexp.MustCompile(`^\[\[?[A-Za-z0-9_.'" -]+\]\]?\s*(#.*)?$`)
+	// tomlKey matches a TOML key/value pair such as root = "patients"
+	tomlKey = regexp.MustCompile(`^[A-Za-z0-9_.'" -]+=`)
+)
+
+// sniffFormat guesses the format of a config from its first line that is not blank or a comment.
+// A config is a mapping, so it can only start with "{" in JSON, and with a table header or a
+// key = value pair in TOML. Anything else is read as YAML.
+func sniffFormat(data []byte) format {
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return formatJSON
+		case tomlTable.MatchString(line), tomlKey.MatchString(line):
+			return formatTOML
+		default:
+			return formatYAML
+		}
+	}
+	return formatJSON
+}
+
+// decode reads a config file in the given format into generic JSON values: maps, lists, strings,
+// float64 numbers, booleans and nil. Dates and times, which YAML and TOML have types for, are
+// kept as the text they were written as, so every format decodes to the same config as JSON.
+func decode(data []byte, configFormat format) (map[string]interface{}, error) {
+	var doc interface{}
+	switch configFormat {
+	case formatJSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	case formatYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		value, err := yamlValue(&node)
+		if err != nil {
+			return nil, err
+		}
+		doc = value
+	case formatTOML:
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(data), &table); err != nil {
+			return nil, err
+		}
+		doc = tomlValue(table)
+	default:
+		return nil, fmt.Errorf("unsupported config format: %v", configFormat)
+	}
+
+	if doc == nil {
+		return nil, nil
+	}
+	config, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config must be an object")
+	}
+
+	// round trip through JSON so that numbers are float64 whatever format they were read from
+	return toJSONValues(config)
+}
+
+func toJSONValues(config map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+// encode writes generic JSON values as a config file in the given format. Objects are written with
+// their keys sorted, and whole numbers are written without a fraction in every format.
+func encode(values map[string]interface{}, configFormat format) ([]byte, error) {
+	var buf bytes.Buffer
+	switch configFormat {
+	case formatJSON:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(values); err != nil {
+			return nil, err
+		}
+	case formatYAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(wholeNumbers(values)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	case formatTOML:
+		encoder := toml.NewEncoder(&buf)
+		encoder.Indent = ""
+		if err := encoder.Encode(wholeNumbers(values)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format: %v", configFormat)
+	}
+	return buf.Bytes(), nil
+}
+
+// wholeNumbers converts float64 values without a fraction into int64, so that YAML and TOML
+// write 2 rather than 2.0.
+func wholeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[key] = wholeNumbers(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = wholeNumbers(item)
+		}
+		return converted
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// yamlValue converts a YAML node into generic values, keeping timestamps as text.
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case 0:
+		// a document with nothing but comments
+		return nil, nil
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		var merged []interface{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, valueNode := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be strings", key.Line)
+			}
+			value, err := yamlValue(valueNode)
+			if err != nil {
+				return nil, err
+			}
+			if key.ShortTag() == "!!merge" {
+				merged = append(merged, value)
+				continue
+			}
+			m[key.Value] = value
+		}
+
+		// keys merged in with << never replace the keys of the mapping itself
+		for _, value := range merged {
+			sources, ok := value.([]interface{})
+			if !ok {
+				sources = []interface{}{value}
+			}
+			for _, source := range sources {
+				sourceMap, ok := source.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("line %d: only mappings can be merged", node.Line)
+				}
+				for key, val := range sourceMap {
+					if _, exists := m[key]; !exists {
+						m[key] = val
+					}
+				}
+			}
+		}
+		return m, nil
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!timestamp" {
+			return node.Value, nil
+		}
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+}
+
+// tomlValue converts the dates and times in a decoded TOML value back into text.
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = tomlValue(item)
+		}
+		return v
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = tomlValue(item)
+		}
+		return list
+	case []interface{}:
+		for i, item := range v {
+			v[i] = tomlValue(item)
+		}
+		return v
+	case time.Time:
+		// local dates and times are decoded into these locations
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format("2006-01-02")
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05.999999999")
+		case "time-local":
+			return v.Format("15:04:05.999999999")
+		default:
+			return v.Format(time.RFC3339Nano)
+		}
+	default:
+		return v
+	}
+}
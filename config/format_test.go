@@ -0,0 +1,151 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		data     string
+		expected format
+	}{
+		{name: "json extension", filePath: "config.json", data: "root: patients", expected: formatJSON},
+		{name: "yaml extension", filePath: "config.yaml", data: `{"root": "patients"}`, expected: formatYAML},
+		{name: "yml extension", filePath: "config.YML", data: "", expected: formatYAML},
+		{name: "toml extension", filePath: "config.toml", data: "", expected: formatTOML},
+		{name: "json content", filePath: "config", data: "\n  {\"root\": \"patients\"}", expected: formatJSON},
+		{name: "yaml content", filePath: "config.conf", data: "# patients\nroot: patients\n", expected: formatYAML},
+		{name: "yaml content with equals sign", filePath: "config", data: "root: a=b\n", expected: formatYAML},
+		{name: "toml key", filePath: "config", data: "# patients\nroot = \"patients\"\n", expected: formatTOML},
+		{name: "toml table", filePath: "config", data: "[mappings]\nID = \"id\"\n", expected: formatTOML},
+		{name: "toml array of tables", filePath: "config", data: "[[steps]] # first\n", expected: formatTOML},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, detectFormat(test.filePath, []byte(test.data)))
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		format      format
+		expected    map[string]interface{}
+		expectedErr bool
+	}{
+		{
+			name:   "yaml",
+			data:   "root: patients\nreference_time: 2025-01-29\nextras:\n  width: 5\n  separator: \"\\n\"\n  ratio: 0.5\n  list: [a, 1]\n",
+			format: formatYAML,
+			expected: map[string]interface{}{
+				"root":           "patients",
+				"reference_time": "2025-01-29",
+				"extras": map[string]interface{}{
+					"width":     float64(5),
+					"separator": "\n",
+					"ratio":     0.5,
+					"list":      []interface{}{"a", float64(1)},
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:   "yaml anchors and merge keys",
+			data:   "dates: &dates\n  format: \"2006-01-02\"\n  unit: years\nextras:\n  <<: *dates\n  unit: months\n",
+			format: formatYAML,
+			expected: map[string]interface{}{
+				"dates":  map[string]interface{}{"format": "2006-01-02", "unit": "years"},
+				"extras": map[string]interface{}{"format": "2006-01-02", "unit": "months"},
+			},
+			expectedErr: false,
+		},
+		{
+			name:   "toml",
+			data:   "root = \"patients\"\nreference_time = 2025-01-29\nstarted = 2025-01-29T08:30:00-05:00\n[extras]\nwidth = 5\nlist = [\"a\", 1]\n[[steps]]\ntype = \"string\"\n",
+			format: formatTOML,
+			expected: map[string]interface{}{
+				"root":           "patients",
+				"reference_time": "2025-01-29",
+				"started":        "2025-01-29T08:30:00-05:00",
+				"extras": map[string]interface{}{
+					"width": float64(5),
+					"list":  []interface{}{"a", float64(1)},
+				},
+				"steps": []interface{}{
+					map[string]interface{}{"type": "string"},
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:        "yaml that is not an object",
+			data:        "- root\n- patients\n",
+			format:      formatYAML,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid toml",
+			data:        "root = patients\n",
+			format:      formatTOML,
+			expectedErr: true,
+		},
+		{
+			name:        "empty yaml",
+			data:        "# nothing here\n",
+			format:      formatYAML,
+			expected:    nil,
+			expectedErr: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := decode([]byte(test.data), test.format)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	values := map[string]interface{}{
+		"root":    "patients",
+		"version": float64(3),
+		"transformations": map[string]interface{}{
+			"age": map[string]interface{}{
+				"type": "calculate",
+				"params": map[string]interface{}{
+					"fields": []interface{}{"DateOfBirth", "CurrentTime"},
+					"extras": map[string]interface{}{
+						"operation":         "time_difference",
+						"format":            "2006-01-02",
+						"decimal_precision": float64(2),
+						"factor":            0.5,
+						"round_to_int":      true,
+					},
+				},
+			},
+		},
+	}
+
+	for _, configFormat := range []format{formatJSON, formatYAML, formatTOML} {
+		t.Run(string(configFormat), func(t *testing.T) {
+			data, err := encode(values, configFormat)
+			require.NoError(t, err)
+			require.NotContains(t, string(data), "3.0")
+
+			decoded, err := decode(data, configFormat)
+			require.NoError(t, err)
+			require.Equal(t, values, decoded)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"havocai-assignment/internal/jsonpath"
+	"havocai-assignment/internal/maputil"
+	"havocai-assignment/models"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WithParams sets values for the ${NAME} placeholders in a config. A param takes precedence over
+// an environment variable with the same name.
+func WithParams(params map[string]string) Option {
+	return func(o *loadOptions) {
+		if o.params == nil {
+			o.params = make(map[string]string, len(params))
+		}
+		for name, val := range params {
+			o.params[name] = val
+		}
+	}
+}
+
+// placeholder matches ${NAME} and ${NAME:-default}, as well as $${ which is written for a literal
+// ${. Anything else between ${ and }, such as ${1}, is left as it is. The replacement extra is not
+// searched for placeholders at all, as its ${name} refers to a group of a regular expression.
+var placeholder = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// wholePlaceholder matches a value that is nothing but a single placeholder.
+var wholePlaceholder = regexp.MustCompile(`^\$\{[A-Za-z_][A-Za-z0-9_]*(:-[^}]*)?\}$`)
+
+// interpolator replaces the placeholders in config values with params and environment variables.
+type interpolator struct {
+	params map[string]string
+}
+
+func (i interpolator) lookup(name string) (string, bool) {
+	if val, ok := i.params[name]; ok {
+		return val, true
+	}
+	return os.LookupEnv(name)
+}
+
+// interpolate replaces the placeholders in every string value of a config except replacement
+// extras, returning a *models.ValidationError listing every placeholder that has no value and no
+// default. The JSON path of every string value is recorded in placeholders, as true when the
+// value was a single placeholder, so that convertTypes can give such values the type they are
+// used as.
+func (i interpolator) interpolate(values map[string]interface{}, placeholders map[string]bool) error {
+	var problems []models.Problem
+	i.interpolateValue("", values, placeholders, &problems)
+	if len(problems) > 0 {
+		return &models.ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (i interpolator) interpolateValue(path string, value interface{}, placeholders map[string]bool, problems *[]models.Problem) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// walk the keys in order so that problems are always reported in the same order
+		for _, key := range maputil.SortedKeys(v) {
+			// the ${name} of a replacement refers to a group of its regular expression
+			if key == "replacement" && strings.HasSuffix(path, ".extras") {
+				continue
+			}
+			v[key] = i.interpolateValue(jsonpath.Join(path, key), v[key], placeholders, problems)
+		}
+		return v
+	case []interface{}:
+		for j, item := range v {
+			v[j] = i.interpolateValue(fmt.Sprintf("%v[%d]", path, j), item, placeholders, problems)
+		}
+		return v
+	case string:
+		placeholders[path] = wholePlaceholder.MatchString(v)
+		return i.interpolateString(path, v, problems)
+	default:
+		return v
+	}
+}
+
+// interpolateString replaces the placeholders in a string. A default is used when the variable is
+// not set or is empty.
+func (i interpolator) interpolateString(path string, str string, problems *[]models.Problem) string {
+	if !strings.Contains(str, "${") {
+		return str
+	}
+	return placeholder.ReplaceAllStringFunc(str, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		groups := placeholder.FindStringSubmatch(match)
+		name, hasDefault, def := groups[1], groups[2] != "", groups[3]
+		if val, ok := i.lookup(name); ok && (val != "" || !hasDefault) {
+			return val
+		}
+		if hasDefault {
+			return def
+		}
+		*problems = append(*problems, models.Problem{Path: path, Message: fmt.Sprintf("%v is not set", name)})
+		return match
+	})
+}
+
+// convertTypes turns the text that placeholders leave in values that must be numbers or booleans,
+// such as a decimal_precision of ${PRECISION}, into numbers and booleans. Only values that were a
+// single placeholder, as recorded in placeholders by JSON path, are converted, and only where a
+// number or a boolean is expected: extras whose schema says so, the required option of mappings
+// and transformations, and keep_namespace_declarations. Every other value keeps its text, even
+// when it looks like a number, and text that is not a number or a boolean is left for validation
+// to report.
+func convertTypes(values map[string]interface{}, placeholders map[string]bool) {
+	c := typeConverter{placeholders: placeholders}
+	c.convert(values, "", "keep_namespace_declarations", models.BoolValue)
+	mappings, _ := values["mappings"].(map[string]interface{})
+	for xmlField, val := range mappings {
+		if mapping, ok := val.(map[string]interface{}); ok {
+			c.convert(mapping, jsonpath.Join("mappings", xmlField), "required", models.BoolValue)
+		}
+	}
+	for _, key := range []string{"definitions", "transformations"} {
+		transformations, _ := values[key].(map[string]interface{})
+		for name, transformation := range transformations {
+			c.convertTransformation(jsonpath.Join(key, name), transformation)
+		}
+	}
+}
+
+// typeConverter converts the values that were a single placeholder.
+type typeConverter struct {
+	placeholders map[string]bool
+}
+
+// convertTransformation converts the values of a transformation, of its steps and of the
+// transformations run by the branches of an if. A transformation that gets its type from a
+// definition is converted once the definition has been used.
+func (c typeConverter) convertTransformation(path string, value interface{}) {
+	transformation, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	c.convert(transformation, path, "required", models.BoolValue)
+	steps, _ := transformation["steps"].([]interface{})
+	for i, step := range steps {
+		c.convertTransformation(fmt.Sprintf("%v.steps[%d]", path, i), step)
+	}
+
+	typ, _ := transformation["type"].(string)
+	schema, ok := models.LookupSchema(typ)
+	params, _ := transformation["params"].(map[string]interface{})
+	extras, _ := params["extras"].(map[string]interface{})
+	if !ok || extras == nil {
+		return
+	}
+	extrasPath := path + ".params.extras"
+	for key, extra := range schema.Extras {
+		c.convert(extras, extrasPath, key, extra.Kind)
+	}
+	if typ == "if" {
+		for _, branch := range []string{"then", "else"} {
+			if result, ok := extras[branch].(map[string]interface{}); ok {
+				c.convertTransformation(jsonpath.Join(extrasPath, branch)+".transformation", result["transformation"])
+			}
+		}
+	}
+}
+
+// convert converts values[key] to the kind when it was a single placeholder whose text can be
+// read as that kind.
+func (c typeConverter) convert(values map[string]interface{}, path string, key string, kind models.ValueKind) {
+	text, ok := values[key].(string)
+	if !ok || !c.placeholders[jsonpath.Join(path, key)] {
+		return
+	}
+	switch kind {
+	case models.NumberValue, models.IntegerValue:
+		number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+			values[key] = number
+		}
+	case models.BoolValue:
+		if b, err := strconv.ParseBool(strings.TrimSpace(text)); err == nil {
+			values[key] = b
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("SITE_CODE", "RI-01")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		name        string
+		params      map[string]string
+		value       string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "no placeholders",
+			value:    "tables/gender.csv",
+			expected: "tables/gender.csv",
+		},
+		{
+			name:     "environment variable",
+			value:    "${SITE_CODE}",
+			expected: "RI-01",
+		},
+		{
+			name:     "placeholders within text",
+			value:    "${TABLES}/${SITE_CODE}/gender.csv",
+			params:   map[string]string{"TABLES": "/etc/tables"},
+			expected: "/etc/tables/RI-01/gender.csv",
+		},
+		{
+			name:     "param takes precedence over environment variable",
+			value:    "${SITE_CODE}",
+			params:   map[string]string{"SITE_CODE": "MI-02"},
+			expected: "MI-02",
+		},
+		{
+			name:     "default when not set",
+			value:    "${REFERENCE_DATE:-2025-01-29}",
+			expected: "2025-01-29",
+		},
+		{
+			name:     "default when empty",
+			value:    "${EMPTY:-Unknown}",
+			expected: "Unknown",
+		},
+		{
+			name:     "empty default",
+			value:    "${UNSET:-}",
+			expected: "",
+		},
+		{
+			name:     "empty value without default",
+			value:    "${EMPTY}",
+			expected: "",
+		},
+		{
+			name:     "value is not interpolated again",
+			value:    "${NESTED}",
+			params:   map[string]string{"NESTED": "${SITE_CODE}"},
+			expected: "${SITE_CODE}",
+		},
+		{
+			name:     "escaped placeholder",
+			value:    "$${first}-$${last}",
+			expected: "${first}-${last}",
+		},
+		{
+			name:     "regular expression group is left as it is",
+			value:    "${1}-$2",
+			expected: "${1}-$2",
+		},
+		{
+			name:     "whole value placeholder with a number stays text",
+			value:    "${SITE_CODE}",
+			params:   map[string]string{"SITE_CODE": "12345"},
+			expected: "12345",
+		},
+		{
+			name:        "not set",
+			value:       "${SITE_CODE}-${FACILITY}",
+			expectedErr: "invalid config: value: FACILITY is not set",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values := map[string]interface{}{"value": test.value}
+			err := interpolator{params: test.params}.interpolate(values, map[string]bool{})
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, values["value"])
+		})
+	}
+}
+
+func TestInterpolateReportsEveryPlaceholder(t *testing.T) {
+	values := map[string]interface{}{
+		"root":           "patients",
+		"reference_time": "${REFERENCE_DATE}",
+		"transformations": map[string]interface{}{
+			"facility": map[string]interface{}{
+				"params": map[string]interface{}{
+					"fields": []interface{}{"FacilityID", "${FACILITY_FIELD}"},
+					"extras": map[string]interface{}{
+						"table_file":      "${TABLES}/${FACILITY_TABLE}",
+						"fail_on_missing": true,
+					},
+				},
+			},
+		},
+	}
+
+	err := interpolator{}.interpolate(values, map[string]bool{})
+	require.EqualError(t, err, `invalid config, 4 problems:
+	reference_time: REFERENCE_DATE is not set
+	transformations.facility.params.extras.table_file: TABLES is not set
+	transformations.facility.params.extras.table_file: FACILITY_TABLE is not set
+	transformations.facility.params.fields[1]: FACILITY_FIELD is not set`)
+}
+
+func TestLoadFileParams(t *testing.T) {
+	_, err := LoadFile("../test/testdata/params/config.yaml")
+	require.EqualError(t, err, "config file "+absPath(t, "../test/testdata/params/config.yaml")+": invalid config: mappings.SiteCode.default: SITE_CODE is not set")
+
+	t.Setenv("SITE_CODE", "MI-02")
+	cfg, err := LoadFile("../test/testdata/params/config.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "MI-02", cfg.Mappings["SiteCode"].Default)
+
+	cfg, err = LoadFile("../test/testdata/params/config.yaml", WithParams(map[string]string{"SITE_CODE": "RI-01", "UNKNOWN_GENDER": "U"}))
+	require.NoError(t, err)
+	require.Equal(t, "RI-01", cfg.Mappings["SiteCode"].Default)
+
+	// the table file from the default is read relative to the config
+	gender := cfg.Transformations["gender"]
+	require.Equal(t, "U", gender.Params.Extras["default"])
+	require.Equal(t, "Male", gender.Params.Extras["table"].(map[string]interface{})["M"])
+}
+
+func TestLoadFileParamsInIncludes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sites"), 0755))
+	files := map[string]string{
+		"config.yaml":      "include: sites/${SITE}.yaml\nroot: patients\n",
+		"sites/RI-01.yaml": "mappings:\n  ID: id\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	cfg, err := LoadFile(filepath.Join(dir, "config.yaml"), WithParams(map[string]string{"SITE": "RI-01"}))
+	require.NoError(t, err)
+	require.Equal(t, "id", cfg.Mappings["ID"].Field)
+}
+
+func TestLoadFilePlaceholderTypes(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	config := `root: ${ROOT}
+mappings:
+  SiteCode:
+    field: site
+    default: ${SITE_CODE}
+    required: ${REQUIRED:-true}
+definitions:
+  years:
+    type: calculate
+    params:
+      fields: [DateOfBirth, CurrentTime]
+      extras:
+        operation: time_difference
+        unit: days
+        round_to_int: ${ROUND:-false}
+transformations:
+  age:
+    type: calculate
+    params:
+      fields: [DateOfBirth, CurrentTime]
+      extras:
+        operation: time_difference
+        format: "2006-01-02"
+        unit: days
+        decimal_precision: ${PRECISION}
+  age_in_days:
+    use: years
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+	overlayPath := filepath.Join(dir, "overlay.yaml")
+	overlay := `transformations:
+  age_in_days:
+    params:
+      extras:
+        decimal_precision: ${PRECISION}
+`
+	require.NoError(t, os.WriteFile(overlayPath, []byte(overlay), 0644))
+
+	params := map[string]string{"ROOT": "12345", "SITE_CODE": "02860", "PRECISION": "2"}
+	cfg, err := LoadFile(configPath, WithParams(params), WithOverlays(overlayPath))
+	require.NoError(t, err)
+
+	// values that must be numbers or booleans take that type, and any other value keeps its text
+	require.Equal(t, "12345", cfg.RootName)
+	require.Equal(t, "02860", cfg.Mappings["SiteCode"].Default)
+	require.True(t, cfg.Mappings["SiteCode"].Required)
+	require.Equal(t, float64(2), cfg.Transformations["age"].Params.Extras["decimal_precision"])
+	require.Equal(t, false, cfg.Transformations["age_in_days"].Params.Extras["round_to_int"])
+	require.Equal(t, float64(2), cfg.Transformations["age_in_days"].Params.Extras["decimal_precision"])
+
+	params["PRECISION"] = "two"
+	_, err = LoadFile(configPath, WithParams(params))
+	require.ErrorContains(t, err, "transformations.age.params.extras.decimal_precision: must be a whole number")
+}
+
+func TestLoadFileReplacementGroups(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+    "root": "patients",
+    "transformations": {
+        "phone": {
+            "type": "string",
+            "params": {
+                "fields": ["Phone"],
+                "extras": {
+                    "operation": "replace",
+                    "pattern": "^(?P<area>\\d{3})(?P<number>\\d{4})$",
+                    "replacement": "${area}-${number}"
+                }
+            }
+        }
+    }
+}`
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+
+	// the groups of a replacement are not read as placeholders
+	cfg, err := LoadFile(configPath)
+	require.NoError(t, err)
+	require.Equal(t, "${area}-${number}", cfg.Transformations["phone"].Params.Extras["replacement"])
+}
+
+func absPath(t *testing.T, path string) string {
+	abs, err := filepath.Abs(path)
+	require.NoError(t, err)
+	return abs
+}
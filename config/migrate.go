@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"havocai-assignment/models"
+	"math"
+	"os"
+)
+
+// migrations upgrade a config from one version of the schema to the next: migrations[0] upgrades
+// version 1 to version 2, and so on up to models.CurrentVersion.
+var migrations = []func(values map[string]interface{}) error{
+	migrateMappingList,
+	migrateTransformationFields,
+}
+
+// defaultRoot is the root that the records of every output were written under before version 3
+// added root to the config.
+const defaultRoot = "patients"
+
+// migrate upgrades a config written for an older version of the schema to the current version,
+// and sets its version. A config without a version is migrated from the version its shape
+// matches, so configs written before versions were added keep working. It reports whether the
+// config was written for an older version.
+func migrate(values map[string]interface{}) (bool, error) {
+	version, err := configVersion(values)
+	if err != nil {
+		return false, err
+	}
+	for _, migration := range migrations[version-1:] {
+		if err := migration(values); err != nil {
+			return false, err
+		}
+	}
+	values["version"] = models.CurrentVersion
+	return version < models.CurrentVersion, nil
+}
+
+// setDefaultRoot sets the root of a config that was written for a version before root was added
+// and that has none. It is only applied once the files of a config have been merged, so that an
+// older included file or overlay does not replace the root set elsewhere.
+func setDefaultRoot(values map[string]interface{}) {
+	if _, ok := values["root"]; !ok {
+		values["root"] = defaultRoot
+	}
+}
+
+// configVersion returns the version of a config, from its version key or else from its shape.
+func configVersion(values map[string]interface{}) (int, error) {
+	if val, ok := values["version"]; ok {
+		number, ok := val.(float64)
+		if !ok || number != math.Trunc(number) {
+			return 0, fmt.Errorf("version must be a whole number")
+		}
+		if number < 1 || number > models.CurrentVersion {
+			return 0, fmt.Errorf("unsupported config version %v, the latest supported version is %d", number, models.CurrentVersion)
+		}
+		return int(number), nil
+	}
+
+	if _, ok := values["mappings"].([]interface{}); ok {
+		return 1, nil
+	}
+	found := false
+	forEachTransformation(values, func(transformation map[string]interface{}) {
+		found = found || isFieldsTransformation(transformation)
+	})
+	if found {
+		return 2, nil
+	}
+	return models.CurrentVersion, nil
+}
+
+// forEachTransformation calls fn for every transformation of a config, including definitions and
+// the steps of both.
+func forEachTransformation(values map[string]interface{}, fn func(transformation map[string]interface{})) {
+	var visit func(val interface{})
+	visit = func(val interface{}) {
+		transformation, ok := val.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fn(transformation)
+		steps, _ := transformation["steps"].([]interface{})
+		for _, step := range steps {
+			visit(step)
+		}
+	}
+	for _, key := range []string{"transformations", "definitions"} {
+		transformations, _ := values[key].(map[string]interface{})
+		for _, transformation := range transformations {
+			visit(transformation)
+		}
+	}
+}
+
+// migrateMappingList upgrades a version 1 config, where mappings is a list with an entry for
+// every output field, to the separate mappings and transformations of version 2:
+//
+//	{"mappings": [{"xml_path": "FirstName", "json_field": "first_name", "transform_rule": "", "transform_fields": []}]}
+func migrateMappingList(values map[string]interface{}) error {
+	list, ok := values["mappings"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	mappings := map[string]interface{}{}
+	transformations, _ := values["transformations"].(map[string]interface{})
+	if transformations == nil {
+		transformations = map[string]interface{}{}
+	}
+	for i, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("mappings[%d]: must be an object", i)
+		}
+		xmlPath, _ := entry["xml_path"].(string)
+		jsonField, _ := entry["json_field"].(string)
+		rule, _ := entry["transform_rule"].(string)
+		if jsonField == "" {
+			return fmt.Errorf("mappings[%d].json_field: is required", i)
+		}
+
+		if rule == "" {
+			if xmlPath == "" {
+				return fmt.Errorf("mappings[%d].xml_path: is required when there is no transform_rule", i)
+			}
+			mappings[xmlPath] = jsonField
+			continue
+		}
+		fields, ok := entry["transform_fields"].([]interface{})
+		if !ok {
+			fields = []interface{}{}
+			if xmlPath != "" {
+				fields = append(fields, xmlPath)
+			}
+		}
+		transformations[jsonField] = map[string]interface{}{"type": rule, "fields": fields}
+	}
+
+	values["mappings"] = mappings
+	if len(transformations) > 0 {
+		values["transformations"] = transformations
+	}
+	return nil
+}
+
+// migrateTransformationFields upgrades a version 2 config, where a transformation only has
+// fields, to the params of version 3:
+//
+//	{"type": "concat", "fields": ["FirstName", "LastName"]}
+//
+// Transformations in definitions and steps are upgraded as well. Version 2 joined the fields of a
+// concat with spaces, which is now the separator extra, and a calculate always gave the years
+// between two YYYY-MM-DD dates, which are now the operation, format and unit extras. Version 3
+// also added root, see setDefaultRoot.
+func migrateTransformationFields(values map[string]interface{}) error {
+	forEachTransformation(values, func(transformation map[string]interface{}) {
+		if !isFieldsTransformation(transformation) {
+			return
+		}
+
+		params := map[string]interface{}{"fields": transformation["fields"]}
+		switch transformation["type"] {
+		case "concat":
+			params["extras"] = map[string]interface{}{"separator": " "}
+		case "calculate":
+			params["extras"] = map[string]interface{}{"operation": "time_difference", "format": "2006-01-02", "unit": "years"}
+		}
+		delete(transformation, "fields")
+		transformation["params"] = params
+	})
+	return nil
+}
+
+// isFieldsTransformation reports whether a transformation has the fields of version 2 in place of
+// params.
+func isFieldsTransformation(transformation interface{}) bool {
+	t, ok := transformation.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasFields := t["fields"]
+	_, hasParams := t["params"]
+	return hasFields && !hasParams
+}
+
+// Migrate reads a config file written for any version of the schema and returns it upgraded to
+// the current version, in the same format. Includes and placeholders are left as they are, and
+// comments are not kept.
+func Migrate(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	configFormat := detectFormat(filePath, data)
+	values, err := decode(data, configFormat)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %v: %w", filePath, err)
+	}
+	if values == nil {
+		return nil, fmt.Errorf("config file %v is empty", filePath)
+	}
+	migrated, err := migrate(values)
+	if err != nil {
+		return nil, fmt.Errorf("config file %v: %w", filePath, err)
+	}
+	if migrated {
+		setDefaultRoot(values)
+	}
+	return encode(values, configFormat)
+}
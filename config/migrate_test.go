@@ -0,0 +1,318 @@
+package config
+
+import (
+	"havocai-assignment/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsReachCurrentVersion(t *testing.T) {
+	require.Equal(t, models.CurrentVersion, len(migrations)+1)
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name             string
+		values           map[string]interface{}
+		expected         map[string]interface{}
+		expectedMigrated bool
+		expectedErr      string
+	}{
+		{
+			name: "version 1 mapping list",
+			values: map[string]interface{}{
+				"mappings": []interface{}{
+					map[string]interface{}{"xml_path": "ID", "json_field": "id", "transform_rule": "", "transform_fields": []interface{}{}},
+					map[string]interface{}{"xml_path": "", "json_field": "name", "transform_rule": "concat", "transform_fields": []interface{}{"FirstName", "LastName"}},
+					map[string]interface{}{"xml_path": "Gender", "json_field": "has_gender", "transform_rule": "exists"},
+					map[string]interface{}{"xml_path": "", "json_field": "age", "transform_rule": "calculate", "transform_fields": []interface{}{"DateOfBirth", "CurrentTime"}},
+				},
+			},
+			expected: map[string]interface{}{
+				"version":  models.CurrentVersion,
+				"mappings": map[string]interface{}{"ID": "id"},
+				"transformations": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type": "concat",
+						"params": map[string]interface{}{
+							"fields": []interface{}{"FirstName", "LastName"},
+							"extras": map[string]interface{}{"separator": " "},
+						},
+					},
+					"has_gender": map[string]interface{}{
+						"type":   "exists",
+						"params": map[string]interface{}{"fields": []interface{}{"Gender"}},
+					},
+					"age": map[string]interface{}{
+						"type": "calculate",
+						"params": map[string]interface{}{
+							"fields": []interface{}{"DateOfBirth", "CurrentTime"},
+							"extras": map[string]interface{}{"operation": "time_difference", "format": "2006-01-02", "unit": "years"},
+						},
+					},
+				},
+			},
+			expectedMigrated: true,
+		},
+		{
+			name: "version 2 transformation fields",
+			values: map[string]interface{}{
+				"mappings": map[string]interface{}{"ID": "id"},
+				"transformations": map[string]interface{}{
+					"name": map[string]interface{}{"type": "concat", "fields": []interface{}{"FirstName", "LastName"}},
+					"age":  map[string]interface{}{"type": "calculate", "fields": []interface{}{"DateOfBirth", "CurrentTime"}},
+				},
+			},
+			expected: map[string]interface{}{
+				"version":  models.CurrentVersion,
+				"mappings": map[string]interface{}{"ID": "id"},
+				"transformations": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type": "concat",
+						"params": map[string]interface{}{
+							"fields": []interface{}{"FirstName", "LastName"},
+							"extras": map[string]interface{}{"separator": " "},
+						},
+					},
+					"age": map[string]interface{}{
+						"type": "calculate",
+						"params": map[string]interface{}{
+							"fields": []interface{}{"DateOfBirth", "CurrentTime"},
+							"extras": map[string]interface{}{"operation": "time_difference", "format": "2006-01-02", "unit": "years"},
+						},
+					},
+				},
+			},
+			expectedMigrated: true,
+		},
+		{
+			name: "version 2 keeps its root",
+			values: map[string]interface{}{
+				"root":            "records",
+				"transformations": map[string]interface{}{"name": map[string]interface{}{"type": "concat", "fields": []interface{}{"FirstName"}}},
+			},
+			expected: map[string]interface{}{
+				"version": models.CurrentVersion,
+				"root":    "records",
+				"transformations": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type": "concat",
+						"params": map[string]interface{}{
+							"fields": []interface{}{"FirstName"},
+							"extras": map[string]interface{}{"separator": " "},
+						},
+					},
+				},
+			},
+			expectedMigrated: true,
+		},
+		{
+			name: "version 2 definitions and steps",
+			values: map[string]interface{}{
+				"definitions": map[string]interface{}{
+					"full_name": map[string]interface{}{"type": "concat", "fields": []interface{}{"FirstName", "LastName"}},
+				},
+				"transformations": map[string]interface{}{
+					"name": map[string]interface{}{
+						"steps": []interface{}{
+							map[string]interface{}{"use": "full_name"},
+							map[string]interface{}{"type": "string", "params": map[string]interface{}{"fields": []interface{}{"$"}}},
+						},
+					},
+					"age": map[string]interface{}{
+						"steps": []interface{}{
+							map[string]interface{}{"type": "calculate", "fields": []interface{}{"DateOfBirth", "CurrentTime"}},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"version": models.CurrentVersion,
+				"definitions": map[string]interface{}{
+					"full_name": map[string]interface{}{
+						"type": "concat",
+						"params": map[string]interface{}{
+							"fields": []interface{}{"FirstName", "LastName"},
+							"extras": map[string]interface{}{"separator": " "},
+						},
+					},
+				},
+				"transformations": map[string]interface{}{
+					"name": map[string]interface{}{
+						"steps": []interface{}{
+							map[string]interface{}{"use": "full_name"},
+							map[string]interface{}{"type": "string", "params": map[string]interface{}{"fields": []interface{}{"$"}}},
+						},
+					},
+					"age": map[string]interface{}{
+						"steps": []interface{}{
+							map[string]interface{}{
+								"type": "calculate",
+								"params": map[string]interface{}{
+									"fields": []interface{}{"DateOfBirth", "CurrentTime"},
+									"extras": map[string]interface{}{"operation": "time_difference", "format": "2006-01-02", "unit": "years"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedMigrated: true,
+		},
+		{
+			name: "current version without a version",
+			values: map[string]interface{}{
+				"root":     "patients",
+				"mappings": map[string]interface{}{"ID": "id"},
+			},
+			expected: map[string]interface{}{
+				"version":  models.CurrentVersion,
+				"root":     "patients",
+				"mappings": map[string]interface{}{"ID": "id"},
+			},
+		},
+		{
+			name: "version is not guessed when it is given",
+			values: map[string]interface{}{
+				"version":         float64(3),
+				"transformations": map[string]interface{}{"name": map[string]interface{}{"type": "concat", "fields": []interface{}{"FirstName"}}},
+			},
+			expected: map[string]interface{}{
+				"version":         models.CurrentVersion,
+				"transformations": map[string]interface{}{"name": map[string]interface{}{"type": "concat", "fields": []interface{}{"FirstName"}}},
+			},
+		},
+		{
+			name:        "newer version",
+			values:      map[string]interface{}{"version": float64(4)},
+			expectedErr: "unsupported config version 4, the latest supported version is 3",
+		},
+		{
+			name:        "version is not a number",
+			values:      map[string]interface{}{"version": "3"},
+			expectedErr: "version must be a whole number",
+		},
+		{
+			name: "version 1 mapping without json_field",
+			values: map[string]interface{}{
+				"mappings": []interface{}{map[string]interface{}{"xml_path": "ID"}},
+			},
+			expectedErr: "mappings[0].json_field: is required",
+		},
+		{
+			name: "version 1 mapping without xml_path",
+			values: map[string]interface{}{
+				"mappings": []interface{}{map[string]interface{}{"json_field": "id", "transform_rule": ""}},
+			},
+			expectedErr: "mappings[0].xml_path: is required when there is no transform_rule",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrated, err := migrate(test.values)
+			if test.expectedErr != "" {
+				require.EqualError(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, test.values)
+			require.Equal(t, test.expectedMigrated, migrated)
+		})
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		configPath string
+		format     format
+	}{
+		{
+			name:       "version 1 json",
+			configPath: "../test/testdata/migrate/v1_config.json",
+			format:     formatJSON,
+		},
+		{
+			name:       "version 2 yaml",
+			configPath: "../test/testdata/migrate/v2_config.yaml",
+			format:     formatYAML,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := Migrate(test.configPath)
+			require.NoError(t, err)
+
+			values, err := decode(data, test.format)
+			require.NoError(t, err)
+			require.Equal(t, float64(models.CurrentVersion), values["version"])
+			require.Equal(t, "patients", values["root"])
+
+			// a migrated config is already at the current version, so migrating it again changes nothing
+			migratedAgain := merge(nil, values)
+			migrated, err := migrate(migratedAgain)
+			require.NoError(t, err)
+			require.False(t, migrated)
+			migratedAgain["version"] = float64(models.CurrentVersion)
+			require.Equal(t, values, migratedAgain)
+		})
+	}
+}
+
+func TestLoadFileMigrates(t *testing.T) {
+	for _, configPath := range []string{"../test/testdata/migrate/v1_config.json", "../test/testdata/migrate/v2_config.yaml"} {
+		t.Run(configPath, func(t *testing.T) {
+			cfg, err := LoadFile(configPath)
+			require.NoError(t, err)
+
+			require.Equal(t, models.CurrentVersion, cfg.Version)
+			require.Equal(t, "patients", cfg.RootName)
+			require.Equal(t, "id", cfg.Mappings["ID"].Field)
+			require.Equal(t, models.Transformation{
+				Type: "concat",
+				Params: models.Params{
+					Fields: []string{"FirstName", "LastName"},
+					Extras: map[string]interface{}{"separator": " "},
+				},
+			}, cfg.Transformations["name"])
+			require.Equal(t, models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{"DateOfBirth", "CurrentTime"},
+					Extras: map[string]interface{}{"operation": "time_difference", "format": "2006-01-02", "unit": "years"},
+				},
+			}, cfg.Transformations["age"])
+		})
+	}
+}
+
+func TestLoadFileDefaultRoot(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml":    "version: 3\nroot: members\nmappings:\n  ID: id\n",
+		"v2_config.yaml": "mappings:\n  ID: id\n",
+		"overlay.yaml":   "transformations:\n  name:\n    type: concat\n    fields: [FirstName, LastName]\n",
+		"v3_config.yaml": "version: 3\nmappings:\n  ID: id\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	// an older overlay does not replace the root of the config it overlays
+	cfg, err := LoadFile(filepath.Join(dir, "config.yaml"), WithOverlays(filepath.Join(dir, "overlay.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, "members", cfg.RootName)
+	require.Equal(t, " ", cfg.Transformations["name"].Params.Extras["separator"])
+
+	// an older config without a root gets the root older versions wrote records under
+	cfg, err = LoadFile(filepath.Join(dir, "v2_config.yaml"), WithOverlays(filepath.Join(dir, "overlay.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, "patients", cfg.RootName)
+
+	// a current config still has to set its root
+	_, err = LoadFile(filepath.Join(dir, "v3_config.yaml"))
+	require.ErrorContains(t, err, "root: is required")
+}
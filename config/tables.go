@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"havocai-assignment/models"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadLookupTables reads the table_file of every lookup transformation into its table, so that
+// tables are read once when the config is loaded rather than for every record. Relative paths
+// are resolved against the directory of the config file.
+func loadLookupTables(cfg *models.Config, baseDir string) error {
+	for jsonField, transformation := range cfg.Transformations {
+		if err := loadTransformationTables(&transformation, baseDir); err != nil {
+			return fmt.Errorf("transformation %v: %w", jsonField, err)
+		}
+		cfg.Transformations[jsonField] = transformation
+	}
+	return nil
+}
+
+func loadTransformationTables(transformation *models.Transformation, baseDir string) error {
+	for i := range transformation.Steps {
+		if err := loadTransformationTables(&transformation.Steps[i], baseDir); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	if transformation.Type == "if" {
+		return loadBranchTables(transformation, baseDir)
+	}
+	if transformation.Type != "lookup" {
+		return nil
+	}
+	file, ok := transformation.Params.Extras["table_file"].(string)
+	if !ok {
+		return nil
+	}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(baseDir, file)
+	}
+	table, err := readLookupTable(file)
+	if err != nil {
+		return err
+	}
+	transformation.Params.Extras["table"] = table
+	return nil
+}
+
+// loadBranchTables loads the tables of the transformations that the then and else branches of an
+// if transformation run. Those transformations are kept in extras as generic JSON, so each is
+// round tripped into its struct and back.
+func loadBranchTables(transformation *models.Transformation, baseDir string) error {
+	for _, branch := range []string{"then", "else"} {
+		result, ok := transformation.Params.Extras[branch].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		definition, ok := result["transformation"]
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(definition)
+		if err != nil {
+			return fmt.Errorf("%v: %w", branch, err)
+		}
+		var nested models.Transformation
+		if err := json.Unmarshal(data, &nested); err != nil {
+			return fmt.Errorf("%v: invalid transformation: %w", branch, err)
+		}
+		if err := loadTransformationTables(&nested, baseDir); err != nil {
+			return fmt.Errorf("%v: %w", branch, err)
+		}
+		if data, err = json.Marshal(nested); err != nil {
+			return fmt.Errorf("%v: %w", branch, err)
+		}
+		var loaded interface{}
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return fmt.Errorf("%v: %w", branch, err)
+		}
+		result["transformation"] = loaded
+	}
+	return nil
+}
+
+// readLookupTable reads a table of codes to values from a JSON object or from a CSV file with two
+// columns, code and value, where the first row is a header.
+func readLookupTable(file string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lookup table: %w", err)
+	}
+
+	table := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		if err := json.Unmarshal(data, &table); err != nil {
+			return nil, fmt.Errorf("error parsing lookup table %v: %w", file, err)
+		}
+	case ".csv":
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = 2
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing lookup table %v: %w", file, err)
+		}
+		for _, row := range rows[min(1, len(rows)):] {
+			table[row[0]] = row[1]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported lookup table file %v, expected .csv or .json", file)
+	}
+	return table, nil
+}
@@ -0,0 +1,169 @@
+// Package converter converts XML into JSON using a config of mappings and transformations.
+// It is the entry point for Go programs that want to embed the conversion directly.
+package converter
+
+import (
+	"context"
+	"fmt"
+	"havocai-assignment/models"
+	"havocai-assignment/parser"
+	"io"
+	"time"
+)
+
+// ErrMaxRecords is returned when the input contains more records than the converter allows.
+var ErrMaxRecords = parser.ErrMaxRecords
+
+// ErrorPolicy decides what happens when a single record cannot be converted.
+type ErrorPolicy int
+
+const (
+	// FailFast stops the conversion at the first record that cannot be converted.
+	FailFast ErrorPolicy = iota
+	// SkipRecord leaves records that cannot be converted out of the output and carries on.
+	SkipRecord
+)
+
+// Format is the layout of the JSON output.
+type Format int
+
+const (
+	// FormatJSON writes a single JSON document with the records in an array under the config's root.
+	FormatJSON Format = iota
+	// FormatJSONLines writes each record as compact JSON on its own line.
+	FormatJSONLines
+)
+
+// Converter converts XML into JSON one record at a time, so memory use stays bounded no matter
+// how large the input is. A Converter can be reused for any number of conversions.
+type Converter struct {
+	cfg          *models.Config
+	transformer  *parser.RecordTransformer
+	now          func() time.Time
+	errorPolicy  ErrorPolicy
+	errorHandler func(error)
+	format       Format
+	maxRecords   int
+	maxDepth     int
+}
+
+// Option configures a Converter.
+type Option func(*Converter)
+
+// WithClock sets the function used for the current time wherever a transformation refers to
+// CurrentTime. It takes precedence over the config's reference_time. By default time.Now is used.
+func WithClock(now func() time.Time) Option {
+	return func(c *Converter) {
+		c.now = now
+	}
+}
+
+// WithErrorPolicy sets what happens when a record cannot be converted. The default is FailFast.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(c *Converter) {
+		c.errorPolicy = policy
+	}
+}
+
+// WithErrorHandler sets a function that is called with a *parser.RecordError for every record
+// that is skipped under the SkipRecord policy.
+func WithErrorHandler(handler func(error)) Option {
+	return func(c *Converter) {
+		c.errorHandler = handler
+	}
+}
+
+// WithOutputFormat sets the layout of the output. The default is FormatJSON.
+func WithOutputFormat(format Format) Option {
+	return func(c *Converter) {
+		c.format = format
+	}
+}
+
+// WithMaxRecords fails the conversion once the input contains more than n records.
+// A limit of 0 means no limit.
+func WithMaxRecords(n int) Option {
+	return func(c *Converter) {
+		c.maxRecords = n
+	}
+}
+
+// WithMaxDepth fails the conversion when elements are nested more than n levels deep,
+// counting the document root as level 1. A limit of 0 means no limit.
+func WithMaxDepth(n int) Option {
+	return func(c *Converter) {
+		c.maxDepth = n
+	}
+}
+
+// FixedClock returns a clock for WithClock that always returns t, so that conversions which use
+// CurrentTime produce the same output every time they are run.
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time {
+		return t
+	}
+}
+
+// ParseTime parses a reference time written as an RFC3339 timestamp (e.g. 2025-01-29T00:00:00Z)
+// or as a date (e.g. 2025-01-29), which is read as midnight UTC. See parser.ParseTime.
+func ParseTime(value string) (time.Time, error) {
+	return parser.ParseTime(value)
+}
+
+// New creates a Converter for the given config. When the config sets a reference_time and no
+// clock is given with WithClock, CurrentTime is pinned to the reference_time.
+// Transformations that refer to each other in a cycle are reported by New.
+func New(cfg *models.Config, opts ...Option) (*Converter, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is required")
+	}
+
+	c := &Converter{
+		cfg: cfg,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// without a clock, the transformer falls back to the config's reference_time
+	var err error
+	c.transformer, err = parser.NewRecordTransformer(cfg, parser.Options{Now: c.now})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Convert reads XML from r and writes the converted JSON to w. Each record is transformed and
+// written as soon as it has been parsed, see parser.StreamWith. Cancelling ctx stops the
+// conversion between records.
+func (c *Converter) Convert(ctx context.Context, r io.Reader, w io.Writer) error {
+	return parser.StreamWith(ctx, r, w, c.transformer, parser.StreamOptions{
+		NewWriter:     c.newWriter,
+		OnRecordError: c.onRecordError,
+		MaxRecords:    c.maxRecords,
+		MaxDepth:      c.maxDepth,
+	})
+}
+
+// onRecordError applies the error policy to a record that cannot be converted.
+func (c *Converter) onRecordError(err *parser.RecordError) error {
+	if c.errorPolicy == FailFast {
+		return err
+	}
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+	}
+	return nil
+}
+
+func (c *Converter) newWriter(w io.Writer) (parser.RecordWriter, error) {
+	switch c.format {
+	case FormatJSON:
+		return parser.NewJSONWriter(w, c.cfg.RootName)
+	case FormatJSONLines:
+		return parser.NewJSONLinesWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %v", c.format)
+	}
+}
@@ -0,0 +1,308 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"havocai-assignment/models"
+	"havocai-assignment/parser"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func patientConfig() *models.Config {
+	return &models.Config{
+		RootName: "patients",
+		Mappings: map[string]models.Mapping{
+			"ID": {Field: "id"},
+		},
+		Transformations: map[string]models.Transformation{
+			"age": {
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{"DateOfBirth", "CurrentTime"},
+					Extras: map[string]interface{}{
+						"operation":                "time_difference",
+						"format":                   "2006-01-02",
+						"unit":                     "years",
+						"adjust_if_day_not_passed": true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func fixedClock() time.Time {
+	return time.Date(2025, time.January, 29, 0, 0, 0, 0, time.UTC)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name              string
+		inputFilePath     string
+		opts              []Option
+		expected          string
+		expectedJSONL     bool
+		expectedErr       error
+		expectedRecordErr bool
+		expectedAnyErr    bool
+		expectedSkips     int
+	}{
+		{
+			name:          "multiple records with clock",
+			inputFilePath: "../test/testdata/basicpatient/multiple_patients.xml",
+			opts:          []Option{WithClock(fixedClock)},
+			expected: `{
+				"patients": [
+					{"id": 12345, "age": 31},
+					{"id": 53425, "age": 104}
+				]
+			}`,
+		},
+		{
+			name:          "json lines output",
+			inputFilePath: "../test/testdata/basicpatient/multiple_patients.xml",
+			opts:          []Option{WithClock(fixedClock), WithOutputFormat(FormatJSONLines)},
+			expected: `{"age":31,"id":12345}
+{"age":104,"id":53425}
+`,
+			expectedJSONL: true,
+		},
+		{
+			name:              "invalid record fails by default",
+			inputFilePath:     "../test/testdata/converter/missing_birth_date.xml",
+			opts:              []Option{WithClock(fixedClock)},
+			expectedRecordErr: true,
+		},
+		{
+			name:          "invalid record skipped",
+			inputFilePath: "../test/testdata/converter/missing_birth_date.xml",
+			opts:          []Option{WithClock(fixedClock), WithErrorPolicy(SkipRecord)},
+			expected: `{
+				"patients": [
+					{"id": 53425, "age": 104}
+				]
+			}`,
+			expectedSkips: 1,
+		},
+		{
+			name:          "too many records",
+			inputFilePath: "../test/testdata/basicpatient/multiple_patients.xml",
+			opts:          []Option{WithClock(fixedClock), WithMaxRecords(1)},
+			expectedErr:   ErrMaxRecords,
+		},
+		{
+			name:          "elements nested up to the limit",
+			inputFilePath: "../test/testdata/basicpatient/multiple_patients.xml",
+			opts:          []Option{WithClock(fixedClock), WithMaxDepth(3)},
+			expected: `{
+				"patients": [
+					{"id": 12345, "age": 31},
+					{"id": 53425, "age": 104}
+				]
+			}`,
+		},
+		{
+			name:          "elements nested too deeply",
+			inputFilePath: "../test/testdata/inputchanges/nested_fields.xml",
+			opts:          []Option{WithClock(fixedClock), WithMaxDepth(3)},
+			expectedErr:   parser.ErrMaxDepth,
+		},
+		{
+			name:           "invalid xml",
+			inputFilePath:  "../test/testdata/basicpatient/invalid_xml.xml",
+			expectedAnyErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input, err := os.Open(test.inputFilePath)
+			require.NoError(t, err)
+			defer input.Close()
+
+			skips := 0
+			opts := append(test.opts, WithErrorHandler(func(err error) {
+				var recordErr *parser.RecordError
+				require.ErrorAs(t, err, &recordErr)
+				skips++
+			}))
+			converter, err := New(patientConfig(), opts...)
+			require.NoError(t, err)
+
+			var output bytes.Buffer
+			err = converter.Convert(context.Background(), input, &output)
+
+			switch {
+			case test.expectedAnyErr:
+				require.Error(t, err)
+			case test.expectedErr != nil:
+				require.ErrorIs(t, err, test.expectedErr)
+			case test.expectedRecordErr:
+				var recordErr *parser.RecordError
+				require.ErrorAs(t, err, &recordErr)
+				require.Equal(t, 0, recordErr.Index)
+			case test.expectedJSONL:
+				require.NoError(t, err)
+				require.Equal(t, test.expected, output.String())
+			default:
+				require.NoError(t, err)
+				var unmarshalledActual map[string]interface{}
+				var unmarshalledExpected map[string]interface{}
+				require.NoError(t, json.Unmarshal(output.Bytes(), &unmarshalledActual))
+				require.NoError(t, json.Unmarshal([]byte(test.expected), &unmarshalledExpected))
+				require.Equal(t, unmarshalledExpected, unmarshalledActual)
+				require.Equal(t, test.expectedSkips, skips)
+			}
+		})
+	}
+}
+
+func TestConvertEmptyInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   Format
+		expected string
+	}{
+		{
+			name:     "empty document",
+			input:    "<Patients></Patients>",
+			format:   FormatJSON,
+			expected: "{\n  \"patients\": []\n}\n",
+		},
+		{
+			name:     "no input",
+			input:    "",
+			format:   FormatJSON,
+			expected: "{\n  \"patients\": []\n}\n",
+		},
+		{
+			name:     "empty document as json lines",
+			input:    "<Patients></Patients>",
+			format:   FormatJSONLines,
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converter, err := New(patientConfig(), WithOutputFormat(test.format))
+			require.NoError(t, err)
+
+			var output bytes.Buffer
+			require.NoError(t, converter.Convert(context.Background(), strings.NewReader(test.input), &output))
+			require.Equal(t, test.expected, output.String())
+		})
+	}
+}
+
+func TestConvertTypeHintSkipped(t *testing.T) {
+	input := `<Patients>
+		<Patient><ID>12345</ID><DateOfBirth>1993-07-06</DateOfBirth></Patient>
+		<Patient><ID>unknown</ID><DateOfBirth>1920-09-10</DateOfBirth></Patient>
+		<Patient><ID>53425</ID><DateOfBirth>1920-09-10</DateOfBirth></Patient>
+	</Patients>`
+	cfg := patientConfig()
+	cfg.Types = models.Types{Hints: map[string]string{"ID": "int"}}
+
+	var skipped []error
+	converter, err := New(cfg, WithClock(fixedClock), WithErrorPolicy(SkipRecord), WithErrorHandler(func(err error) {
+		skipped = append(skipped, err)
+	}))
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	require.NoError(t, converter.Convert(context.Background(), strings.NewReader(input), &output))
+	require.JSONEq(t, `{"patients": [{"id": 12345, "age": 31}, {"id": 53425, "age": 104}]}`, output.String())
+
+	require.Len(t, skipped, 1)
+	var recordErr *parser.RecordError
+	require.ErrorAs(t, skipped[0], &recordErr)
+	require.Equal(t, 1, recordErr.Index)
+	require.ErrorIs(t, skipped[0], parser.ErrTypeHint)
+}
+
+func TestConvertCancelled(t *testing.T) {
+	converter, err := New(patientConfig())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = converter.Convert(ctx, strings.NewReader("<Patients></Patients>"), &bytes.Buffer{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewRequiresConfig(t *testing.T) {
+	_, err := New(nil)
+	require.Error(t, err)
+}
+
+func TestNewReportsCycles(t *testing.T) {
+	cfg := patientConfig()
+	cfg.Transformations["a"] = models.Transformation{Type: "concat", Params: models.Params{Fields: []string{"$b"}}}
+	cfg.Transformations["b"] = models.Transformation{Type: "concat", Params: models.Params{Fields: []string{"$a"}}}
+
+	_, err := New(cfg)
+	require.ErrorContains(t, err, "cycle: a -> b -> a")
+}
+
+func TestReferenceTime(t *testing.T) {
+	tests := []struct {
+		name          string
+		referenceTime string
+		opts          []Option
+		expectedAge   float64
+		expectedErr   bool
+	}{
+		{
+			name:          "reference time from config",
+			referenceTime: "2025-01-29",
+			expectedAge:   39,
+			expectedErr:   false,
+		},
+		{
+			name:          "reference time as timestamp",
+			referenceTime: "2015-06-01T12:00:00Z",
+			expectedAge:   29,
+			expectedErr:   false,
+		},
+		{
+			name:          "clock takes precedence over config",
+			referenceTime: "2015-06-01",
+			opts:          []Option{WithClock(fixedClock)},
+			expectedAge:   39,
+			expectedErr:   false,
+		},
+		{
+			name:          "invalid reference time",
+			referenceTime: "29/01/2025",
+			expectedErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := patientConfig()
+			cfg.ReferenceTime = test.referenceTime
+
+			converter, err := New(cfg, test.opts...)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			input := "<Patients><Patient><ID>12345</ID><DateOfBirth>1985-07-15</DateOfBirth></Patient></Patients>"
+			var output bytes.Buffer
+			require.NoError(t, converter.Convert(context.Background(), strings.NewReader(input), &output))
+
+			var actual map[string][]map[string]interface{}
+			require.NoError(t, json.Unmarshal(output.Bytes(), &actual))
+			require.Equal(t, test.expectedAge, actual["patients"][0]["age"])
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package fieldpath parses the path expressions that address fields within a record, such as
+// "Address/Street", "Provider/@ID" or "Phone[2]". It is shared by the parser, which resolves
+// paths, and by config validation, which reports paths that cannot be parsed.
+package fieldpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// attrPrefix marks an attribute, e.g. "@ID".
+const attrPrefix = "@"
+
+// Segment is a single step of a field path such as "Provider", "@ID" or "Phone[2]".
+type Segment struct {
+	Name string
+	// Index is the 1-based position within repeated elements, or 0 when no index was given
+	Index int
+}
+
+// IsPath reports whether a field is a path expression rather than a bare element or attribute name.
+func IsPath(field string) bool {
+	return strings.ContainsAny(field, "/@[]")
+}
+
+// Parse splits a field path like "Provider/@ID" or "Phone[2]" into its segments.
+func Parse(field string) ([]Segment, error) {
+	parts := strings.Split(field, "/")
+	segments := make([]Segment, 0, len(parts))
+	for i, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", field)
+		}
+
+		segment := Segment{Name: part}
+		if open := strings.Index(part, "["); open != -1 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, fmt.Errorf("invalid path %q: missing ] in %q", field, part)
+			}
+			index, err := strconv.Atoi(part[open+1 : len(part)-1])
+			if err != nil || index < 1 {
+				return nil, fmt.Errorf("invalid path %q: index in %q must be a positive integer", field, part)
+			}
+			segment.Name = part[:open]
+			segment.Index = index
+		} else if strings.Contains(part, "]") {
+			return nil, fmt.Errorf("invalid path %q: missing [ in %q", field, part)
+		}
+
+		if segment.Name == "" {
+			return nil, fmt.Errorf("invalid path %q: missing element name in %q", field, part)
+		}
+		if strings.HasPrefix(segment.Name, attrPrefix) && i != len(parts)-1 {
+			return nil, fmt.Errorf("invalid path %q: attribute %q must be the last segment", field, segment.Name)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
@@ -0,0 +1,89 @@
+package fieldpath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		field       string
+		expected    []Segment
+		expectedErr bool
+	}{
+		{
+			name:  "nested element",
+			field: "Address/Street",
+			expected: []Segment{
+				{Name: "Address"},
+				{Name: "Street"},
+			},
+			expectedErr: false,
+		},
+		{
+			name:  "attribute of nested element",
+			field: "Provider/@ID",
+			expected: []Segment{
+				{Name: "Provider"},
+				{Name: "@ID"},
+			},
+			expectedErr: false,
+		},
+		{
+			name:  "indexed element",
+			field: "Phone[2]",
+			expected: []Segment{
+				{Name: "Phone", Index: 2},
+			},
+			expectedErr: false,
+		},
+		{
+			name:        "empty segment",
+			field:       "Address//Street",
+			expectedErr: true,
+		},
+		{
+			name:        "attribute before end of path",
+			field:       "@ID/Street",
+			expectedErr: true,
+		},
+		{
+			name:        "index is not a number",
+			field:       "Phone[first]",
+			expectedErr: true,
+		},
+		{
+			name:        "index starts at 1",
+			field:       "Phone[0]",
+			expectedErr: true,
+		},
+		{
+			name:        "missing ]",
+			field:       "Phone[x",
+			expectedErr: true,
+		},
+		{
+			name:        "missing [",
+			field:       "Phone2]",
+			expectedErr: true,
+		},
+		{
+			name:        "index without element name",
+			field:       "Address/[2]",
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Parse(test.field)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+// Package jsonpath builds the JSON paths, such as `mappings["Address/Street"].field`, that config
+// problems are reported under. It is shared by config validation, by the checks of the built-in
+// transformation types and by config loading, so that every problem names its value the same way.
+package jsonpath
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// plainKey matches keys that can be written in a JSON path without quoting.
+var plainKey = regexp.MustCompile(`^[A-Za-z0-9_@$#:-]+$`)
+
+// Join appends a key to a JSON path, quoting keys such as "address.street" that would otherwise
+// be read as more than one key.
+func Join(path string, key string) string {
+	if plainKey.MatchString(key) {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
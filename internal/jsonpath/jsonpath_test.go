@@ -0,0 +1,27 @@
+package jsonpath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		key      string
+		expected string
+	}{
+		{name: "first key", path: "", key: "root", expected: "root"},
+		{name: "plain key", path: "transformations", key: "age", expected: "transformations.age"},
+		{name: "attribute key", path: "mappings", key: "@ID", expected: "mappings.@ID"},
+		{name: "key with a dot", path: "transformations", key: "address.street", expected: `transformations["address.street"]`},
+		{name: "key with a slash", path: "mappings", key: "Address/Street", expected: `mappings["Address/Street"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, Join(test.path, test.key))
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// Package maputil holds helpers for the maps that configs and records are made of. It is shared
+// by the parser and by config validation, so that both walk config maps in the same order.
+package maputil
+
+import "sort"
+
+// SortedKeys returns the keys of a map in sorted order, so that maps are walked the same way on
+// every run.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
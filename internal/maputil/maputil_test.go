@@ -0,0 +1,23 @@
+package maputil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortedKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        map[string]int
+		expected []string
+	}{
+		{name: "nil map", m: nil, expected: []string{}},
+		{name: "keys in sorted order", m: map[string]int{"b": 2, "a": 1, "@ID": 0}, expected: []string{"@ID", "a", "b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, SortedKeys(test.m))
+		})
+	}
+}
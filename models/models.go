@@ -1,14 +1,101 @@
 package models
 
+import "encoding/json"
+
+// CurrentVersion is the version of the config schema described by Config. Configs written for an
+// older version are migrated to it when they are loaded.
+const CurrentVersion = 3
+
 type Config struct {
+	// Version is the version of the config schema the config was written for. A config that is
+	// built in Go can leave it at 0, which is read as CurrentVersion.
+	Version         int                       `json:"version,omitempty"`
 	RootName        string                    `json:"root"`
-	Mappings        map[string]string         `json:"mappings"`
+	Mappings        map[string]Mapping        `json:"mappings"`
 	Transformations map[string]Transformation `json:"transformations"`
+	// RecordPath selects the elements that are records, e.g. "Export/Patients/Patient".
+	// When empty, every child of the document root is a record.
+	RecordPath string `json:"record_path"`
+	// ForceArray lists elements that are always parsed as a list, even when a record has only one.
+	ForceArray []string `json:"force_array"`
+	// Namespaces binds the prefixes used for field names in this config to namespace URIs.
+	Namespaces map[string]string `json:"namespaces"`
+	// KeepNamespaceDeclarations keeps xmlns attributes in the parsed records.
+	KeepNamespaceDeclarations bool `json:"keep_namespace_declarations"`
+	// ReferenceTime pins the time used for CurrentTime, as an RFC3339 timestamp or a YYYY-MM-DD
+	// date, so that reprocessing the same input always produces the same output.
+	ReferenceTime string `json:"reference_time"`
+	// Types controls the types that element and attribute values are parsed as.
+	Types Types `json:"types"`
+	// Dates holds the date extras ("format", "timezone" and "output_timezone") used to read dates
+	// for type hints and the "date" output_type, and by transformations that do not set their own.
+	Dates map[string]interface{} `json:"dates"`
+}
+
+// Types controls how the text of elements and attributes is turned into values when the XML is
+// parsed, before any mapping or transformation runs.
+type Types struct {
+	// Inference is "auto" (the default), "strings-only" or "strict".
+	Inference string `json:"inference,omitempty"`
+	// Hints sets the type of an element, or of an attribute when the name starts with "@",
+	// to string, int, float, bool or date regardless of Inference.
+	Hints map[string]string `json:"hints,omitempty"`
+}
+
+// Mapping is a 1:1 mapping of an input field to the output field named by Field. In a config
+// file it can be written as just the output field name, or as an object to set field options.
+type Mapping struct {
+	Field string `json:"field"`
+	FieldOptions
+}
+
+func (m *Mapping) UnmarshalJSON(data []byte) error {
+	var field string
+	if err := json.Unmarshal(data, &field); err == nil {
+		*m = Mapping{Field: field}
+		return nil
+	}
+
+	// decode into a type without this method to use the default struct decoding
+	type mapping Mapping
+	return json.Unmarshal(data, (*mapping)(m))
+}
+
+func (m Mapping) MarshalJSON() ([]byte, error) {
+	if m.FieldOptions.isZero() {
+		return json.Marshal(m.Field)
+	}
+
+	type mapping Mapping
+	return json.Marshal(mapping(m))
 }
 
 type Transformation struct {
-	Type   string `json:"type"`
+	Type   string `json:"type,omitempty"`
 	Params Params `json:"params"`
+	// Steps runs a list of transformations in order, each able to use the result of the one
+	// before it as the field "$". When set, Type and Params are not used.
+	Steps []Transformation `json:"steps,omitempty"`
+	FieldOptions
+}
+
+// FieldOptions control what happens when a mapping or transformation produces no value, which
+// is when the input field is missing or the value is null or an empty string, and the type
+// the value is written as.
+type FieldOptions struct {
+	// Coalesce lists input fields that are tried in order when there is no value.
+	Coalesce []string `json:"coalesce,omitempty"`
+	// Default is used when neither the value nor any of the Coalesce fields have a value.
+	Default interface{} `json:"default,omitempty"`
+	// Required fails the record when the output field is still without a value.
+	Required bool `json:"required,omitempty"`
+	// OutputType converts the value to string, int, float, bool, date or null-if-empty before
+	// it is written. When empty, the value keeps the type it was parsed or produced as.
+	OutputType string `json:"output_type,omitempty"`
+}
+
+func (o FieldOptions) isZero() bool {
+	return len(o.Coalesce) == 0 && o.Default == nil && !o.Required && o.OutputType == ""
 }
 
 type Params struct {
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]Mapping
+	}{
+		{
+			name:  "output field name",
+			input: `{"ID": "id"}`,
+			expected: map[string]Mapping{
+				"ID": {Field: "id"},
+			},
+		},
+		{
+			name:  "output type",
+			input: `{"ID": {"field": "id", "output_type": "string"}}`,
+			expected: map[string]Mapping{
+				"ID": {Field: "id", FieldOptions: FieldOptions{OutputType: "string"}},
+			},
+		},
+		{
+			name:  "field options",
+			input: `{"PhoneNumber": {"field": "phone", "coalesce": ["HomePhone"], "default": "unknown", "required": true}}`,
+			expected: map[string]Mapping{
+				"PhoneNumber": {
+					Field: "phone",
+					FieldOptions: FieldOptions{
+						Coalesce: []string{"HomePhone"},
+						Default:  "unknown",
+						Required: true,
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var actual map[string]Mapping
+			require.NoError(t, json.Unmarshal([]byte(test.input), &actual))
+			require.Equal(t, test.expected, actual)
+
+			// mappings should be written back in the same form they were read
+			output, err := json.Marshal(actual)
+			require.NoError(t, err)
+			require.JSONEq(t, test.input, string(output))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ValueKind is the JSON type that an extra must have.
+type ValueKind int
+
+const (
+	// AnyValue accepts a value of any type.
+	AnyValue ValueKind = iota
+	StringValue
+	NumberValue
+	// IntegerValue is a whole number. Numbers in a JSON config are decoded as float64, so whole
+	// float64 values are accepted.
+	IntegerValue
+	BoolValue
+	ObjectValue
+	ListValue
+	// StringOrListValue is a string or a list of strings.
+	StringOrListValue
+)
+
+// ExtraSchema describes a single extra of a transformation type.
+type ExtraSchema struct {
+	Kind     ValueKind
+	Required bool
+	// OneOf lists the values a string extra may have. Any value is accepted when empty.
+	OneOf []string
+}
+
+// Schema describes the params that a transformation type accepts.
+type Schema struct {
+	// MinFields and MaxFields bound the number of fields. A MaxFields of 0 means no limit.
+	MinFields int
+	MaxFields int
+	Extras    map[string]ExtraSchema
+	// AllowUnknownExtras accepts extras that are not listed in Extras. Extras named in fields are
+	// always accepted, as fields fall back to extras for constant values.
+	AllowUnknownExtras bool
+	// Check validates rules that depend on more than one param, such as extras that are only
+	// required by some operations. The paths of the problems it returns are relative to params.
+	Check func(params Params) []Problem
+}
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]Schema)
+)
+
+// RegisterSchema sets the schema that Validate checks the params of a transformation type
+// against, replacing any schema already registered for the type. parser.Register calls it for
+// transformers that come with a schema, which is how the built-in types register theirs.
+func RegisterSchema(name string, schema Schema) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	schemas[name] = schema
+}
+
+// LookupSchema returns the schema registered for a transformation type.
+func LookupSchema(name string) (Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	schema, ok := schemas[name]
+	return schema, ok
+}
+
+// dateExtras describes the extras used to read dates, which are also the settings of the
+// config's dates.
+var dateExtras = map[string]ExtraSchema{
+	"format":          {Kind: StringOrListValue},
+	"timezone":        {Kind: StringValue},
+	"output_timezone": {Kind: StringValue},
+}
+
+// WithDateExtras adds the extras used to read dates to the extras of a schema, for transformation
+// types that read dates. See CheckTimeZones for checking their time zones.
+func WithDateExtras(extras map[string]ExtraSchema) map[string]ExtraSchema {
+	for key, extra := range dateExtras {
+		extras[key] = extra
+	}
+	return extras
+}
+
+// CheckTimeZones checks that the timezone and output_timezone in values are known time zones.
+// Problems are reported under path, e.g. "extras" for the extras of a transformation.
+func CheckTimeZones(path string, values map[string]interface{}) []Problem {
+	var problems []Problem
+	for _, key := range []string{"timezone", "output_timezone"} {
+		name, ok := values[key].(string)
+		if !ok {
+			continue
+		}
+		if _, err := time.LoadLocation(name); err != nil {
+			problems = append(problems, Problem{Path: path + "." + key, Message: fmt.Sprintf("unknown time zone %q", name)})
+		}
+	}
+	return problems
+}
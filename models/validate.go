@@ -0,0 +1,385 @@
+package models
+
+import (
+	"fmt"
+	"havocai-assignment/internal/fieldpath"
+	"havocai-assignment/internal/jsonpath"
+	"havocai-assignment/internal/maputil"
+	"math"
+	"strings"
+	"time"
+)
+
+// Problem is a single problem found in a config. Path is the JSON path of the value with the
+// problem, e.g. transformations.age.params.extras.unit.
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v: %v", p.Path, p.Message)
+}
+
+// ValidationError is returned by Validate with every problem found in a config.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("invalid config: %v", e.Problems[0])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config, %d problems:", len(e.Problems))
+	for _, problem := range e.Problems {
+		fmt.Fprintf(&b, "\n\t%v", problem)
+	}
+	return b.String()
+}
+
+// Validate checks the config and the params of every transformation against the schema of its
+// type, so that mistakes are found before any input is converted. All problems are reported at
+// once in a *ValidationError, in the order they appear in the config.
+func (c *Config) Validate() error {
+	var problems []Problem
+	if c.Version != 0 && c.Version != CurrentVersion {
+		problems = append(problems, Problem{Path: "version", Message: fmt.Sprintf("unsupported version %d, must be %d", c.Version, CurrentVersion)})
+	}
+	if c.RootName == "" {
+		problems = append(problems, Problem{Path: "root", Message: "is required"})
+	}
+
+	var outputs outputFields
+	for _, xmlField := range maputil.SortedKeys(c.Mappings) {
+		path := jsonpath.Join("mappings", xmlField)
+		mapping := c.Mappings[xmlField]
+		problems = append(problems, validateFieldPath(path, xmlField)...)
+		if mapping.Field == "" {
+			problems = append(problems, Problem{Path: path + ".field", Message: "is required"})
+		} else {
+			problems = append(problems, outputs.add(path+".field", mapping.Field)...)
+		}
+		problems = append(problems, validateFieldOptions(path, mapping.FieldOptions)...)
+		for i, field := range mapping.Coalesce {
+			if strings.HasPrefix(field, "$") {
+				problems = append(problems, Problem{Path: fmt.Sprintf("%v.coalesce[%d]", path, i), Message: "cannot refer to an output field, as mappings are applied before transformations"})
+			}
+		}
+	}
+
+	for _, jsonField := range maputil.SortedKeys(c.Transformations) {
+		path := jsonpath.Join("transformations", jsonField)
+		transformation := c.Transformations[jsonField]
+		problems = append(problems, outputs.add(path, jsonField)...)
+		problems = append(problems, ValidateTransformation(path, transformation)...)
+		problems = append(problems, validateFieldOptions(path, transformation.FieldOptions)...)
+	}
+
+	if c.RecordPath != "" {
+		for _, name := range strings.Split(strings.Trim(c.RecordPath, "/"), "/") {
+			if name == "" {
+				problems = append(problems, Problem{Path: "record_path", Message: fmt.Sprintf("invalid record path %q: empty segment", c.RecordPath)})
+				break
+			}
+		}
+	}
+
+	if c.ReferenceTime != "" && !isReferenceTime(c.ReferenceTime) {
+		problems = append(problems, Problem{Path: "reference_time", Message: "must be an RFC3339 timestamp or a YYYY-MM-DD date"})
+	}
+
+	switch c.Types.Inference {
+	case "", "auto", "strings-only", "strict":
+	default:
+		problems = append(problems, Problem{Path: "types.inference", Message: oneOfMessage(c.Types.Inference, []string{"auto", "strings-only", "strict"})})
+	}
+	for _, name := range maputil.SortedKeys(c.Types.Hints) {
+		if hint := c.Types.Hints[name]; !contains(hintTypes, hint) {
+			problems = append(problems, Problem{Path: jsonpath.Join("types.hints", name), Message: oneOfMessage(hint, hintTypes)})
+		}
+	}
+
+	for _, key := range maputil.SortedKeys(c.Dates) {
+		extra, ok := dateExtras[key]
+		if !ok {
+			problems = append(problems, Problem{Path: jsonpath.Join("dates", key), Message: "is not a supported setting"})
+			continue
+		}
+		if message := checkExtra(extra, c.Dates[key]); message != "" {
+			problems = append(problems, Problem{Path: jsonpath.Join("dates", key), Message: message})
+		}
+	}
+	problems = append(problems, CheckTimeZones("dates", c.Dates)...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+var (
+	hintTypes   = []string{"string", "int", "float", "bool", "date"}
+	outputTypes = append(append([]string{}, hintTypes...), "null-if-empty")
+)
+
+// outputFields collects the output field names of a config, so that names that would be written
+// to the same place in the output record are reported when the config is loaded.
+type outputFields []outputField
+
+// outputField is an output field name split into its names, e.g. "contact.phones[]" is
+// ["contact", "phones"] and appends.
+type outputField struct {
+	path    string
+	field   string
+	names   []string
+	appends bool
+}
+
+// add checks an output field name and that it does not conflict with the names added before it:
+// a name cannot be nested in another name, as in "x" and "x.y", and a name cannot be both
+// appended to and set, as in "x" and "x[]".
+func (o *outputFields) add(path string, field string) []Problem {
+	names := strings.Split(field, ".")
+	for i, name := range names {
+		if i < len(names)-1 && strings.HasSuffix(name, outputAppendSuffix) {
+			return []Problem{{Path: path, Message: fmt.Sprintf("invalid output field %q: only the last name can be an array", field)}}
+		}
+		if strings.TrimSuffix(name, outputAppendSuffix) == "" {
+			return []Problem{{Path: path, Message: fmt.Sprintf("invalid output field %q: empty name", field)}}
+		}
+	}
+	last := names[len(names)-1]
+	current := outputField{path: path, field: field, appends: strings.HasSuffix(last, outputAppendSuffix)}
+	current.names = append(names[:len(names)-1], strings.TrimSuffix(last, outputAppendSuffix))
+
+	var problems []Problem
+	for _, other := range *o {
+		if current.conflicts(other) {
+			problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("output field %q conflicts with %q of %v", field, other.field, other.path)})
+		}
+	}
+	*o = append(*o, current)
+	return problems
+}
+
+// conflicts reports whether two output fields cannot both be written to an output record.
+func (f outputField) conflicts(other outputField) bool {
+	shorter, longer := f.names, other.names
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	for i, name := range shorter {
+		if longer[i] != name {
+			return false
+		}
+	}
+	if len(shorter) == len(longer) {
+		return f.appends != other.appends
+	}
+	return true
+}
+
+// outputAppendSuffix marks an output field that collects values into an array.
+const outputAppendSuffix = "[]"
+
+func validateFieldOptions(path string, opts FieldOptions) []Problem {
+	var problems []Problem
+	for i, field := range opts.Coalesce {
+		fieldPath := fmt.Sprintf("%v.coalesce[%d]", path, i)
+		if field == "" {
+			problems = append(problems, Problem{Path: fieldPath, Message: "must not be empty"})
+			continue
+		}
+		problems = append(problems, validateFieldPath(fieldPath, field)...)
+	}
+	if opts.OutputType != "" && !contains(outputTypes, opts.OutputType) {
+		problems = append(problems, Problem{Path: path + ".output_type", Message: oneOfMessage(opts.OutputType, outputTypes)})
+	}
+	return problems
+}
+
+// ValidateTransformation checks a transformation against the schema of its type, and the steps of
+// a transformation made of steps. Problems are reported under path, so transformation types that
+// hold other transformations in their extras can use it to check them.
+func ValidateTransformation(path string, transformation Transformation) []Problem {
+	if len(transformation.Steps) > 0 {
+		var problems []Problem
+		if transformation.Type != "" {
+			problems = append(problems, Problem{Path: path + ".type", Message: "cannot be used together with steps"})
+		}
+		for i, step := range transformation.Steps {
+			problems = append(problems, ValidateTransformation(fmt.Sprintf("%v.steps[%d]", path, i), step)...)
+		}
+		return problems
+	}
+
+	if transformation.Type == "" {
+		return []Problem{{Path: path + ".type", Message: "is required"}}
+	}
+	schema, ok := LookupSchema(transformation.Type)
+	if !ok {
+		return []Problem{{Path: path + ".type", Message: fmt.Sprintf("unknown transformation type %q", transformation.Type)}}
+	}
+	return validateParams(path+".params", schema, transformation.Params)
+}
+
+func validateParams(path string, schema Schema, params Params) []Problem {
+	var problems []Problem
+	switch {
+	case schema.MinFields > 0 && schema.MinFields == schema.MaxFields && len(params.Fields) != schema.MinFields:
+		problems = append(problems, Problem{Path: path + ".fields", Message: fmt.Sprintf("requires exactly %d %v", schema.MinFields, plural(schema.MinFields, "field"))})
+	case len(params.Fields) < schema.MinFields:
+		problems = append(problems, Problem{Path: path + ".fields", Message: fmt.Sprintf("requires at least %d %v", schema.MinFields, plural(schema.MinFields, "field"))})
+	case schema.MaxFields > 0 && len(params.Fields) > schema.MaxFields:
+		problems = append(problems, Problem{Path: path + ".fields", Message: fmt.Sprintf("accepts at most %d %v", schema.MaxFields, plural(schema.MaxFields, "field"))})
+	}
+	for i, field := range params.Fields {
+		fieldPath := fmt.Sprintf("%v.fields[%d]", path, i)
+		if field == "" {
+			problems = append(problems, Problem{Path: fieldPath, Message: "must not be empty"})
+			continue
+		}
+		problems = append(problems, validateFieldPath(fieldPath, field)...)
+	}
+
+	extrasPath := path + ".extras"
+	for _, key := range maputil.SortedKeys(params.Extras) {
+		extra, ok := schema.Extras[key]
+		if !ok {
+			if !schema.AllowUnknownExtras && !contains(params.Fields, key) {
+				problems = append(problems, Problem{Path: jsonpath.Join(extrasPath, key), Message: "is not a supported extra"})
+			}
+			continue
+		}
+		if message := checkExtra(extra, params.Extras[key]); message != "" {
+			problems = append(problems, Problem{Path: jsonpath.Join(extrasPath, key), Message: message})
+		}
+	}
+	for _, key := range maputil.SortedKeys(schema.Extras) {
+		if _, ok := params.Extras[key]; schema.Extras[key].Required && !ok {
+			problems = append(problems, Problem{Path: jsonpath.Join(extrasPath, key), Message: "is required"})
+		}
+	}
+
+	if schema.Check != nil {
+		for _, problem := range schema.Check(params) {
+			problem.Path = path + "." + problem.Path
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// validateFieldPath checks that a field given as a path expression, such as "Provider/@ID", can
+// be parsed. References to output fields, which start with "$", are not paths.
+func validateFieldPath(path string, field string) []Problem {
+	if strings.HasPrefix(field, "$") || !fieldpath.IsPath(field) {
+		return nil
+	}
+	if _, err := fieldpath.Parse(field); err != nil {
+		return []Problem{{Path: path, Message: err.Error()}}
+	}
+	return nil
+}
+
+// checkExtra returns a message describing why the value does not match the extra's schema, or
+// an empty string when it does.
+func checkExtra(extra ExtraSchema, val interface{}) string {
+	switch extra.Kind {
+	case StringValue:
+		str, ok := val.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(extra.OneOf) > 0 && !contains(extra.OneOf, str) {
+			return oneOfMessage(str, extra.OneOf)
+		}
+	case NumberValue:
+		if _, ok := toFloat(val); !ok {
+			return "must be a number"
+		}
+	case IntegerValue:
+		if number, ok := toFloat(val); !ok || number != math.Trunc(number) {
+			return "must be a whole number"
+		}
+	case BoolValue:
+		if _, ok := val.(bool); !ok {
+			return "must be true or false"
+		}
+	case ObjectValue:
+		if _, ok := val.(map[string]interface{}); !ok {
+			return "must be an object"
+		}
+	case ListValue:
+		if _, ok := val.([]interface{}); !ok {
+			return "must be a list"
+		}
+	case StringOrListValue:
+		if !isStringOrList(val) {
+			return "must be a string or a list of strings"
+		}
+	}
+	return ""
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func isStringOrList(val interface{}) bool {
+	switch v := val.(type) {
+	case string, []string:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+func isReferenceTime(value string) bool {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func oneOfMessage(val string, allowed []string) string {
+	return fmt.Sprintf("unsupported value %q, must be one of: %v", val, strings.Join(allowed, ", "))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func contains(list []string, val string) bool {
+	for _, item := range list {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The built-in transformation types register their schemas from the parser package, so these
+// tests use a schema of their own. The built-in schemas are tested in the parser package.
+func init() {
+	RegisterSchema("test_fields", Schema{MinFields: 1})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           Config
+		expectedProblems []Problem
+	}{
+		{
+			name: "valid config",
+			config: Config{
+				RootName: "patients",
+				Mappings: map[string]Mapping{
+					"@ID": {Field: "id", FieldOptions: FieldOptions{OutputType: "string"}},
+				},
+				Transformations: map[string]Transformation{
+					"name": {Type: "test_fields", Params: Params{Fields: []string{"FirstName", "LastName"}}},
+				},
+				ReferenceTime: "2025-01-29",
+				Types: Types{
+					Inference: "strict",
+					Hints:     map[string]string{"Zip": "string"},
+				},
+				Dates: map[string]interface{}{"format": []interface{}{"2006-01-02", "01/02/2006"}, "timezone": "America/New_York"},
+			},
+			expectedProblems: nil,
+		},
+		{
+			name:   "missing root",
+			config: Config{},
+			expectedProblems: []Problem{
+				{Path: "root", Message: "is required"},
+			},
+		},
+		{
+			name:   "unsupported version",
+			config: Config{Version: 2, RootName: "patients"},
+			expectedProblems: []Problem{
+				{Path: "version", Message: "unsupported version 2, must be 3"},
+			},
+		},
+		{
+			name: "mapping problems",
+			config: Config{
+				RootName: "patients",
+				Mappings: map[string]Mapping{
+					"Address/Street": {FieldOptions: FieldOptions{Coalesce: []string{"", "$street"}}},
+					"ID":             {Field: "id", FieldOptions: FieldOptions{OutputType: "integer"}},
+				},
+			},
+			expectedProblems: []Problem{
+				{Path: `mappings["Address/Street"].field`, Message: "is required"},
+				{Path: `mappings["Address/Street"].coalesce[0]`, Message: "must not be empty"},
+				{Path: `mappings["Address/Street"].coalesce[1]`, Message: "cannot refer to an output field, as mappings are applied before transformations"},
+				{Path: "mappings.ID.output_type", Message: `unsupported value "integer", must be one of: string, int, float, bool, date, null-if-empty`},
+			},
+		},
+		{
+			name: "output field names",
+			config: Config{
+				RootName: "patients",
+				Mappings: map[string]Mapping{
+					"City":   {Field: "address..city"},
+					"Email":  {Field: "contact[].email"},
+					"Home":   {Field: "contact.phones[]"},
+					"Mobile": {Field: "contact.phones[]"},
+					"Street": {Field: "address"},
+					"Work":   {Field: "contact.phones"},
+				},
+				Transformations: map[string]Transformation{
+					"address.street": {Type: "test_fields", Params: Params{Fields: []string{"Street"}}},
+				},
+			},
+			expectedProblems: []Problem{
+				{Path: "mappings.City.field", Message: `invalid output field "address..city": empty name`},
+				{Path: "mappings.Email.field", Message: `invalid output field "contact[].email": only the last name can be an array`},
+				{Path: "mappings.Work.field", Message: `output field "contact.phones" conflicts with "contact.phones[]" of mappings.Home.field`},
+				{Path: "mappings.Work.field", Message: `output field "contact.phones" conflicts with "contact.phones[]" of mappings.Mobile.field`},
+				{Path: `transformations["address.street"]`, Message: `output field "address.street" conflicts with "address" of mappings.Street.field`},
+			},
+		},
+		{
+			name: "field paths",
+			config: Config{
+				RootName:   "patients",
+				RecordPath: "Export//Patient",
+				Mappings: map[string]Mapping{
+					"Phone[x":      {Field: "phone"},
+					"Provider/@ID": {Field: "provider_id", FieldOptions: FieldOptions{Coalesce: []string{"@ID/Provider"}}},
+				},
+				Transformations: map[string]Transformation{
+					"name": {Type: "test_fields", Params: Params{Fields: []string{"Name[0]", "$age", "LastName"}}},
+				},
+			},
+			expectedProblems: []Problem{
+				{Path: `mappings["Phone[x"]`, Message: `invalid path "Phone[x": missing ] in "Phone[x"`},
+				{Path: `mappings["Provider/@ID"].coalesce[0]`, Message: `invalid path "@ID/Provider": attribute "@ID" must be the last segment`},
+				{Path: "transformations.name.params.fields[0]", Message: `invalid path "Name[0]": index in "Name[0]" must be a positive integer`},
+				{Path: "record_path", Message: `invalid record path "Export//Patient": empty segment`},
+			},
+		},
+		{
+			name: "transformation type",
+			config: Config{
+				RootName: "patients",
+				Transformations: map[string]Transformation{
+					"name":           {Params: Params{Fields: []string{"FirstName"}}},
+					"address.street": {Type: "concatenate", Params: Params{Fields: []string{"Street"}}},
+				},
+			},
+			expectedProblems: []Problem{
+				{Path: `transformations["address.street"].type`, Message: `unknown transformation type "concatenate"`},
+				{Path: "transformations.name.type", Message: "is required"},
+			},
+		},
+		{
+			name: "reference time and types",
+			config: Config{
+				RootName:      "patients",
+				ReferenceTime: "29/01/2025",
+				Types: Types{
+					Inference: "guess",
+					Hints:     map[string]string{"@MRN": "text"},
+				},
+			},
+			expectedProblems: []Problem{
+				{Path: "reference_time", Message: "must be an RFC3339 timestamp or a YYYY-MM-DD date"},
+				{Path: "types.inference", Message: `unsupported value "guess", must be one of: auto, strings-only, strict`},
+				{Path: "types.hints.@MRN", Message: `unsupported value "text", must be one of: string, int, float, bool, date`},
+			},
+		},
+		{
+			name: "dates",
+			config: Config{
+				RootName: "patients",
+				Dates: map[string]interface{}{
+					"format":          []interface{}{"2006-01-02", 2006},
+					"timezone":        "America/Nowhere",
+					"output_timezone": "Asia/Tokyo",
+					"locale":          "en",
+				},
+			},
+			expectedProblems: []Problem{
+				{Path: "dates.format", Message: "must be a string or a list of strings"},
+				{Path: "dates.locale", Message: "is not a supported setting"},
+				{Path: "dates.timezone", Message: `unknown time zone "America/Nowhere"`},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.Validate()
+			if test.expectedProblems == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, test.expectedProblems, validationErr.Problems)
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{Problems: []Problem{{Path: "root", Message: "is required"}}}
+	require.EqualError(t, err, "invalid config: root: is required")
+
+	err.Problems = append(err.Problems, Problem{Path: "transformations.age.type", Message: "is required"})
+	require.EqualError(t, err, "invalid config, 2 problems:\n\troot: is required\n\ttransformations.age.type: is required")
+}
+
+func TestRegisterSchema(t *testing.T) {
+	RegisterSchema("test_upper", Schema{
+		MinFields: 1,
+		MaxFields: 1,
+		Extras: map[string]ExtraSchema{
+			"locale": {Kind: StringValue},
+		},
+	})
+
+	cfg := Config{
+		RootName: "patients",
+		Transformations: map[string]Transformation{
+			"name": {Type: "test_upper", Params: Params{
+				Fields: []string{"Name"},
+				Extras: map[string]interface{}{"locale": 1},
+			}},
+		},
+	}
+	var validationErr *ValidationError
+	require.ErrorAs(t, cfg.Validate(), &validationErr)
+	require.Equal(t, []Problem{
+		{Path: "transformations.name.params.extras.locale", Message: "must be a string"},
+	}, validationErr.Problems)
+}
@@ -0,0 +1,48 @@
+package parser
+
+import "time"
+
+// calendarDiff returns the number of whole years, months and days from start to end, counted the
+// way ages are: a year or month is only complete once its day has been reached. A month is not
+// complete when the day does not exist in the end month, so someone born on Feb 29 turns a year
+// older on Mar 1 in years that are not leap years. When end is before start, each part is
+// negative. The time of day is ignored.
+func calendarDiff(start time.Time, end time.Time) (years int, months int, days int) {
+	startDate := dateOf(start)
+	endDate := dateOf(end.In(start.Location()))
+	if endDate.Before(startDate) {
+		years, months, days = calendarDiff(end, start)
+		return -years, -months, -days
+	}
+
+	totalMonths := (endDate.Year()-startDate.Year())*12 + int(endDate.Month()-startDate.Month())
+	if endDate.Day() < startDate.Day() {
+		totalMonths--
+	}
+
+	// the day the last complete month ended, clamped to the length of its month so that
+	// Jan 31 plus one month is the end of February rather than early March
+	anchor := time.Date(startDate.Year(), startDate.Month()+time.Month(totalMonths), 1, 0, 0, 0, 0, time.UTC)
+	anchor = anchor.AddDate(0, 0, min(startDate.Day(), daysInMonth(anchor))-1)
+
+	days = int(endDate.Sub(anchor).Hours() / 24)
+	return totalMonths / 12, totalMonths % 12, days
+}
+
+// calendarDays returns the number of calendar days from start to end, counted between their dates
+// in the time zone of start, so that a day made shorter or longer by daylight saving time still
+// counts as one day. The time of day is ignored.
+func calendarDays(start time.Time, end time.Time) int {
+	return int(dateOf(end.In(start.Location())).Sub(dateOf(start)).Hours() / 24)
+}
+
+// dateOf returns the calendar date of t as midnight UTC, so that differences in days are not
+// affected by daylight saving time.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysInMonth returns the number of days in the month of t.
+func daysInMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarDiff(t *testing.T) {
+	tests := []struct {
+		name           string
+		start          string
+		end            string
+		expectedYears  int
+		expectedMonths int
+		expectedDays   int
+	}{
+		{name: "same day", start: "1985-07-15", end: "1985-07-15"},
+		{name: "day before birthday", start: "1985-07-15", end: "2025-07-14", expectedYears: 39, expectedMonths: 11, expectedDays: 29},
+		{name: "on birthday", start: "1985-07-15", end: "2025-07-15", expectedYears: 40},
+		{name: "birthday in a leap year", start: "1990-03-01", end: "2024-02-29", expectedYears: 33, expectedMonths: 11, expectedDays: 28},
+		{name: "born on leap day, day before Mar 1", start: "2000-02-29", end: "2023-02-28", expectedYears: 22, expectedMonths: 11, expectedDays: 30},
+		{name: "born on leap day, Mar 1", start: "2000-02-29", end: "2023-03-01", expectedYears: 23, expectedDays: 1},
+		{name: "born on leap day, leap day", start: "2000-02-29", end: "2024-02-29", expectedYears: 24},
+		{name: "pediatric age", start: "2021-11-20", end: "2025-01-29", expectedYears: 3, expectedMonths: 2, expectedDays: 9},
+		{name: "end of month to shorter month", start: "2025-01-31", end: "2025-02-28", expectedDays: 28},
+		{name: "end of month to next month", start: "2025-01-31", end: "2025-03-01", expectedMonths: 1, expectedDays: 1},
+		{name: "end before start", start: "2025-03-05", end: "2025-01-05", expectedMonths: -2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, err := time.Parse("2006-01-02", test.start)
+			require.NoError(t, err)
+			end, err := time.Parse("2006-01-02", test.end)
+			require.NoError(t, err)
+
+			years, months, days := calendarDiff(start, end)
+			require.Equal(t, test.expectedYears, years)
+			require.Equal(t, test.expectedMonths, months)
+			require.Equal(t, test.expectedDays, days)
+		})
+	}
+}
+
+func TestCalendarDays(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		expected int
+	}{
+		{name: "same day", start: time.Date(2025, 1, 5, 8, 0, 0, 0, time.UTC), end: time.Date(2025, 1, 5, 20, 0, 0, 0, time.UTC), expected: 0},
+		{name: "across midnight", start: time.Date(2025, 1, 5, 23, 0, 0, 0, time.UTC), end: time.Date(2025, 1, 6, 1, 0, 0, 0, time.UTC), expected: 1},
+		{name: "across the start of daylight saving time", start: time.Date(2025, 3, 8, 12, 0, 0, 0, newYork), end: time.Date(2025, 3, 10, 12, 0, 0, 0, newYork), expected: 2},
+		{name: "across the end of daylight saving time", start: time.Date(2025, 11, 1, 0, 30, 0, 0, newYork), end: time.Date(2025, 11, 3, 0, 0, 0, 0, newYork), expected: 2},
+		{name: "end before start", start: time.Date(2025, 1, 29, 0, 0, 0, 0, time.UTC), end: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), expected: -24},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, calendarDays(test.start, test.end))
+		})
+	}
+}
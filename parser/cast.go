@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateOutputLayout is the layout of values with the "date" output_type.
+const dateOutputLayout = "2006-01-02"
+
+// castValue converts a value to the given output_type, reading dates with the given dateParser.
+// Values that are null or an empty string are left as they are, except by null-if-empty, and
+// each item of a repeated element is converted on its own.
+func castValue(val interface{}, outputType string, dates dateParser) (interface{}, error) {
+	if outputType == "null-if-empty" {
+		if isEmpty(val) {
+			return nil, nil
+		}
+		return val, nil
+	}
+	if outputType == "" || isEmpty(val) {
+		return val, nil
+	}
+
+	if list, ok := val.([]interface{}); ok {
+		cast := make([]interface{}, len(list))
+		for i, item := range list {
+			castItem, err := castValue(item, outputType, dates)
+			if err != nil {
+				return nil, err
+			}
+			cast[i] = castItem
+		}
+		return cast, nil
+	}
+
+	cast, ok, err := convertValue(val, outputType, dates)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v (%T) to %v", ErrOutputType, val, val, outputType)
+	}
+	return cast, nil
+}
+
+// convertValue converts a single value to the given type, reporting whether it could be converted.
+func convertValue(val interface{}, typ string, dates dateParser) (interface{}, bool, error) {
+	switch typ {
+	case "string":
+		cast, ok := castString(val)
+		return cast, ok, nil
+	case "int":
+		cast, ok := castInt(val)
+		return cast, ok, nil
+	case "float":
+		cast, ok := castFloat(val)
+		return cast, ok, nil
+	case "bool":
+		cast, ok := castBool(val)
+		return cast, ok, nil
+	case "date":
+		cast, ok := castDate(val, dates)
+		return cast, ok, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported type: %v", typ)
+	}
+}
+
+func castString(val interface{}) (interface{}, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case map[string]interface{}:
+		return nil, false
+	default:
+		return fmt.Sprintf("%v", v), true
+	}
+}
+
+func castInt(val interface{}) (interface{}, bool) {
+	switch v := val.(type) {
+	case bool:
+		return nil, false
+	case string:
+		// strings such as "01234" are kept as strings when parsed, but are still whole numbers
+		intVal, err := strconv.Atoi(strings.TrimSpace(v))
+		return intVal, err == nil
+	}
+
+	number, err := toNumber(val)
+	if err != nil || number != math.Trunc(number) {
+		return nil, false
+	}
+	return int(number), true
+}
+
+func castFloat(val interface{}) (interface{}, bool) {
+	if _, ok := val.(bool); ok {
+		return nil, false
+	}
+	number, err := toNumber(val)
+	return number, err == nil
+}
+
+func castBool(val interface{}) (interface{}, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	case string:
+		boolVal, err := strconv.ParseBool(strings.TrimSpace(v))
+		return boolVal, err == nil
+	}
+	return nil, false
+}
+
+// newCastDateParser creates the dateParser used by type hints and the "date" output_type from
+// the config's dates. Without a format, an RFC3339 timestamp or a YYYY-MM-DD date is accepted.
+func newCastDateParser(dates map[string]interface{}) (dateParser, error) {
+	p, err := newDateParser(dates)
+	if err != nil {
+		return dateParser{}, err
+	}
+	if _, ok := dates["format"]; !ok {
+		p.layouts = []string{time.RFC3339, dateOutputLayout}
+	}
+	return p, nil
+}
+
+// castDate reads a date with the dateParser and returns it as YYYY-MM-DD, in the output time zone
+// when there is one.
+func castDate(val interface{}, dates dateParser) (interface{}, bool) {
+	switch v := val.(type) {
+	case time.Time:
+		return dates.output(v).Format(dateOutputLayout), true
+	case string:
+		if date, err := dates.parse(v); err == nil {
+			return dates.output(date).Format(dateOutputLayout), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,336 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCastValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		val         interface{}
+		outputType  string
+		dates       map[string]interface{}
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:        "no output type",
+			val:         12345,
+			outputType:  "",
+			expected:    12345,
+			expectedErr: false,
+		},
+		{
+			name:        "int to string",
+			val:         12345,
+			outputType:  "string",
+			expected:    "12345",
+			expectedErr: false,
+		},
+		{
+			name:        "float to string",
+			val:         1000.5,
+			outputType:  "string",
+			expected:    "1000.5",
+			expectedErr: false,
+		},
+		{
+			name:        "whole float to string",
+			val:         float64(39),
+			outputType:  "string",
+			expected:    "39",
+			expectedErr: false,
+		},
+		{
+			name:        "bool to string",
+			val:         true,
+			outputType:  "string",
+			expected:    "true",
+			expectedErr: false,
+		},
+		{
+			name:        "nested element to string",
+			val:         map[string]interface{}{"Street": "123 Havoc Way"},
+			outputType:  "string",
+			expectedErr: true,
+		},
+		{
+			name:        "string with leading zero to int",
+			val:         "01234",
+			outputType:  "int",
+			expected:    1234,
+			expectedErr: false,
+		},
+		{
+			name:        "whole float to int",
+			val:         float64(39),
+			outputType:  "int",
+			expected:    39,
+			expectedErr: false,
+		},
+		{
+			name:        "fraction to int",
+			val:         39.5,
+			outputType:  "int",
+			expectedErr: true,
+		},
+		{
+			name:        "text to int",
+			val:         "John",
+			outputType:  "int",
+			expectedErr: true,
+		},
+		{
+			name:        "bool to int",
+			val:         true,
+			outputType:  "int",
+			expectedErr: true,
+		},
+		{
+			name:        "int to float",
+			val:         39,
+			outputType:  "float",
+			expected:    float64(39),
+			expectedErr: false,
+		},
+		{
+			name:        "string to float",
+			val:         "1000.50",
+			outputType:  "float",
+			expected:    1000.5,
+			expectedErr: false,
+		},
+		{
+			name:        "string to bool",
+			val:         "false",
+			outputType:  "bool",
+			expected:    false,
+			expectedErr: false,
+		},
+		{
+			name:        "one to bool",
+			val:         1,
+			outputType:  "bool",
+			expected:    true,
+			expectedErr: false,
+		},
+		{
+			name:        "number to bool",
+			val:         2,
+			outputType:  "bool",
+			expectedErr: true,
+		},
+		{
+			name:        "timestamp to date",
+			val:         "1985-07-15T10:30:00Z",
+			outputType:  "date",
+			expected:    "1985-07-15",
+			expectedErr: false,
+		},
+		{
+			name:        "date to date",
+			val:         "1985-07-15",
+			outputType:  "date",
+			expected:    "1985-07-15",
+			expectedErr: false,
+		},
+		{
+			name:        "text to date",
+			val:         "07/15/1985",
+			outputType:  "date",
+			expectedErr: true,
+		},
+		{
+			name:        "date in one of the configured formats",
+			val:         "07/15/1985",
+			outputType:  "date",
+			dates:       map[string]interface{}{"format": []interface{}{"2006-01-02", "01/02/2006"}},
+			expected:    "1985-07-15",
+			expectedErr: false,
+		},
+		{
+			name:        "timestamp to date in output time zone",
+			val:         "1985-07-15T22:30:00-04:00",
+			outputType:  "date",
+			dates:       map[string]interface{}{"output_timezone": "UTC"},
+			expected:    "1985-07-16",
+			expectedErr: false,
+		},
+		{
+			name:        "date without offset read in time zone",
+			val:         "1985-07-15 22:30",
+			outputType:  "date",
+			dates:       map[string]interface{}{"format": "2006-01-02 15:04", "timezone": "America/New_York", "output_timezone": "Asia/Tokyo"},
+			expected:    "1985-07-16",
+			expectedErr: false,
+		},
+		{
+			name:        "empty string to null",
+			val:         "",
+			outputType:  "null-if-empty",
+			expected:    nil,
+			expectedErr: false,
+		},
+		{
+			name:        "value kept by null-if-empty",
+			val:         "John",
+			outputType:  "null-if-empty",
+			expected:    "John",
+			expectedErr: false,
+		},
+		{
+			name:        "empty string is not cast",
+			val:         "",
+			outputType:  "int",
+			expected:    "",
+			expectedErr: false,
+		},
+		{
+			name:        "each repeated item is cast",
+			val:         []interface{}{123, "0456"},
+			outputType:  "string",
+			expected:    []interface{}{"123", "0456"},
+			expectedErr: false,
+		},
+		{
+			name:        "unsupported output type",
+			val:         12345,
+			outputType:  "decimal",
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dates, err := newCastDateParser(test.dates)
+			require.NoError(t, err)
+
+			actual, err := castValue(test.val, test.outputType, dates)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOutputType(t *testing.T) {
+	cfg := &models.Config{
+		Mappings: map[string]models.Mapping{
+			"@ID":      {Field: "id", FieldOptions: models.FieldOptions{OutputType: "string"}},
+			"LastName": {Field: "last_name", FieldOptions: models.FieldOptions{OutputType: "int"}},
+		},
+	}
+
+	actual, err := TransformRecord(map[string]interface{}{"@ID": 12345}, cfg, Options{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": "12345"}, actual)
+
+	_, err = TransformRecord(map[string]interface{}{"@ID": 12345, "LastName": "Doe"}, cfg, Options{})
+	require.ErrorIs(t, err, ErrOutputType)
+	require.EqualError(t, err, `field "last_name": value cannot be converted to output_type: Doe (string) to int`)
+}
+
+func TestOutputTypeStringFromXML(t *testing.T) {
+	input := `<Patients>
+		<Patient ID="12345678901234567890"><Balance>1.50</Balance><Copay>1.50</Copay><Zip>02860</Zip></Patient>
+	</Patients>`
+	cfg := &models.Config{
+		RootName: "patients",
+		Mappings: map[string]models.Mapping{
+			"ID":      {Field: "id", FieldOptions: models.FieldOptions{OutputType: "string"}},
+			"Balance": {Field: "balance", FieldOptions: models.FieldOptions{OutputType: "string"}},
+			"Copay":   {Field: "copay"},
+			"Address": {Field: "zip", FieldOptions: models.FieldOptions{OutputType: "string", Coalesce: []string{"Zip"}}},
+		},
+	}
+
+	// the text of fields mapped to string is kept as written rather than parsed as a number first
+	var output strings.Builder
+	require.NoError(t, Stream(strings.NewReader(input), &output, cfg))
+	require.JSONEq(t, `{"patients": [{"id": "12345678901234567890", "balance": "1.50", "copay": 1.5, "zip": "02860"}]}`, output.String())
+
+	require.Equal(t, map[string]string{
+		"Address": "string", "@Address": "string",
+		"Balance": "string", "@Balance": "string",
+		"ID": "string", "@ID": "int",
+		"Zip": "string", "@Zip": "string",
+	}, mappingHints(&models.Config{
+		Mappings: cfg.Mappings,
+		Types:    models.Types{Hints: map[string]string{"@ID": "int"}},
+	}))
+}
+
+func TestOutputTypeNullIfEmptyFromXML(t *testing.T) {
+	input := `<Patients>
+		<Patient ID="1"><Race></Race><Ethnicity></Ethnicity></Patient>
+		<Patient ID="2"><Race/></Patient>
+		<Patient ID="3"></Patient>
+		<Patient ID="4"><Race>White</Race></Patient>
+	</Patients>`
+	cfg := &models.Config{
+		RootName: "patients",
+		Mappings: map[string]models.Mapping{
+			"@ID":       {Field: "id"},
+			"Race":      {Field: "race", FieldOptions: models.FieldOptions{OutputType: "null-if-empty"}},
+			"Ethnicity": {Field: "ethnicity"},
+		},
+	}
+
+	// an element that is there but empty is written as null, while a missing element and an empty
+	// element of another mapping are left out
+	var output strings.Builder
+	require.NoError(t, Stream(strings.NewReader(input), &output, cfg))
+	require.JSONEq(t, `{"patients": [
+		{"id": 1, "race": null},
+		{"id": 2, "race": null},
+		{"id": 3},
+		{"id": 4, "race": "White"}
+	]}`, output.String())
+}
+
+func TestConfigDates(t *testing.T) {
+	cfg := &models.Config{
+		Dates: map[string]interface{}{
+			"format":   []interface{}{"2006-01-02", "01/02/2006"},
+			"timezone": "America/New_York",
+		},
+		Types: models.Types{Hints: map[string]string{"LastVisit": "date"}},
+		Mappings: map[string]models.Mapping{
+			"LastVisit":   {Field: "last_visit"},
+			"DateOfBirth": {Field: "birth_date", FieldOptions: models.FieldOptions{OutputType: "date"}},
+		},
+		Transformations: map[string]models.Transformation{
+			"age": {
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{"DateOfBirth", "CurrentTime"},
+					Extras: map[string]interface{}{"operation": "time_difference", "unit": "years"},
+				},
+			},
+		},
+	}
+	input := []byte(`<Patients><Patient><DateOfBirth>07/15/1985</DateOfBirth><LastVisit>01/02/2025</LastVisit></Patient></Patients>`)
+
+	// type hints, output_type date and transformations without date extras all use the config's dates
+	records, err := ParseXML(input, cfg)
+	require.NoError(t, err)
+	require.Equal(t, "2025-01-02", records[0]["LastVisit"])
+
+	now := time.Date(2025, time.January, 29, 0, 0, 0, 0, time.UTC)
+	actual, err := TransformRecord(records[0], cfg, Options{Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"last_visit": "2025-01-02", "birth_date": "1985-07-15", "age": float64(39)}, actual)
+
+	cfg.Dates = map[string]interface{}{"timezone": "America/Nowhere"}
+	_, err = TransformRecord(records[0], cfg, Options{})
+	require.ErrorContains(t, err, "dates: invalid timezone")
+	_, err = ParseXML(input, cfg)
+	require.ErrorContains(t, err, "dates: invalid timezone")
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/internal/maputil"
+	"havocai-assignment/models"
+	"strings"
+)
+
+// outputRefPrefix marks a field that refers to an output field instead of an input field,
+// e.g. "$age". A bare "$" refers to the result of the previous step in a list of steps.
+const outputRefPrefix = "$"
+
+// outputRef returns the name a computed output field is available under to other transformations.
+func outputRef(jsonField string) string {
+	return outputRefPrefix + jsonField
+}
+
+// transformationOrder returns the output fields of the config's transformations in an order where
+// every transformation comes after the transformations it refers to. Transformations that do not
+// depend on each other are ordered by name. A cycle of references is reported as an error.
+func transformationOrder(transformations map[string]models.Transformation) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(transformations))
+	order := make([]string, 0, len(transformations))
+	var path []string
+
+	var visit func(jsonField string) error
+	visit = func(jsonField string) error {
+		switch state[jsonField] {
+		case visited:
+			return nil
+		case visiting:
+			// the path from the first occurrence of this field is the cycle
+			for i, field := range path {
+				if field == jsonField {
+					cycle := append(path[i:], jsonField)
+					return fmt.Errorf("transformations refer to each other in a cycle: %v", strings.Join(cycle, " -> "))
+				}
+			}
+		}
+
+		state[jsonField] = visiting
+		path = append(path, jsonField)
+		for _, dependency := range transformationDependencies(transformations[jsonField], transformations) {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[jsonField] = visited
+		order = append(order, jsonField)
+		return nil
+	}
+
+	for _, jsonField := range maputil.SortedKeys(transformations) {
+		if err := visit(jsonField); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// transformationDependencies returns the output fields of other transformations that a
+// transformation refers to, in its fields, its coalesce fields, its steps or anywhere within its
+// extras.
+func transformationDependencies(transformation models.Transformation, transformations map[string]models.Transformation) []string {
+	var dependencies []string
+	seen := make(map[string]bool)
+
+	var collect func(value interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			name, ok := strings.CutPrefix(v, outputRefPrefix)
+			if !ok || seen[name] {
+				return
+			}
+			if _, isTransformation := transformations[name]; isTransformation {
+				seen[name] = true
+				dependencies = append(dependencies, name)
+			}
+		case []string:
+			for _, item := range v {
+				collect(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]interface{}:
+			for _, key := range maputil.SortedKeys(v) {
+				collect(v[key])
+			}
+		case models.Transformation:
+			collect(v.Coalesce)
+			collect(v.Params.Fields)
+			collect(v.Params.Extras)
+			for _, step := range v.Steps {
+				collect(step)
+			}
+		}
+	}
+	collect(transformation)
+	return dependencies
+}
+
+// runTransformation produces the value of a single output field. When the transformation has
+// steps, they are run in order and each step can refer to the result of the one before it as "$".
+func runTransformation(scope map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+	if len(transformation.Steps) == 0 {
+		transformer, ok := Lookup(transformation.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown transformation type %q", transformation.Type)
+		}
+		return transformer.Transform(scope, transformation, opts)
+	}
+
+	defer delete(scope, outputRefPrefix)
+
+	var result interface{}
+	for i, step := range transformation.Steps {
+		if i > 0 {
+			scope[outputRefPrefix] = result
+		}
+
+		var err error
+		result, err = runTransformation(scope, step, opts)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return result, nil
+}
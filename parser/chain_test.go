@@ -0,0 +1,276 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"havocai-assignment/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformationOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		transformations map[string]models.Transformation
+		expected        []string
+		expectedErr     bool
+	}{
+		{
+			name: "independent transformations ordered by name",
+			transformations: map[string]models.Transformation{
+				"name": {Type: "concat", Params: models.Params{Fields: []string{"FirstName"}}},
+				"age":  {Type: "calculate", Params: models.Params{Fields: []string{"DateOfBirth", "CurrentTime"}}},
+			},
+			expected:    []string{"age", "name"},
+			expectedErr: false,
+		},
+		{
+			name: "dependency comes first",
+			transformations: map[string]models.Transformation{
+				"age_in_months": {Type: "calculate", Params: models.Params{Fields: []string{"$age", "months_per_year"}}},
+				"age":           {Type: "calculate", Params: models.Params{Fields: []string{"DateOfBirth", "CurrentTime"}}},
+				"summary":       {Type: "concat", Params: models.Params{Fields: []string{"$name", "$age_in_months"}}},
+				"name":          {Type: "concat", Params: models.Params{Fields: []string{"FirstName"}}},
+			},
+			expected:    []string{"age", "age_in_months", "name", "summary"},
+			expectedErr: false,
+		},
+		{
+			name: "dependency within steps and extras",
+			transformations: map[string]models.Transformation{
+				"a": {Steps: []models.Transformation{
+					{Type: "concat", Params: models.Params{Fields: []string{"$c"}}},
+				}},
+				"b": {Type: "custom", Params: models.Params{Extras: map[string]interface{}{
+					"when": map[string]interface{}{"field": "$a"},
+				}}},
+				"c": {Type: "concat", Params: models.Params{Fields: []string{"FirstName"}}},
+			},
+			expected:    []string{"c", "a", "b"},
+			expectedErr: false,
+		},
+		{
+			name: "dependency in coalesce",
+			transformations: map[string]models.Transformation{
+				"b_display": {
+					Type:         "concat",
+					Params:       models.Params{Fields: []string{"Nickname"}},
+					FieldOptions: models.FieldOptions{Coalesce: []string{"$z_name"}},
+				},
+				"z_name": {Type: "concat", Params: models.Params{Fields: []string{"FirstName"}}},
+			},
+			expected:    []string{"z_name", "b_display"},
+			expectedErr: false,
+		},
+		{
+			name: "reference to mapped field is not a dependency",
+			transformations: map[string]models.Transformation{
+				"label": {Type: "concat", Params: models.Params{Fields: []string{"$id", "$name"}}},
+				"name":  {Type: "concat", Params: models.Params{Fields: []string{"FirstName"}}},
+			},
+			expected:    []string{"name", "label"},
+			expectedErr: false,
+		},
+		{
+			name: "cycle",
+			transformations: map[string]models.Transformation{
+				"a": {Type: "concat", Params: models.Params{Fields: []string{"$b"}}},
+				"b": {Type: "concat", Params: models.Params{Fields: []string{"$c"}}},
+				"c": {Type: "concat", Params: models.Params{Fields: []string{"$a"}}},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "refers to itself",
+			transformations: map[string]models.Transformation{
+				"a": {Type: "concat", Params: models.Params{Fields: []string{"$a"}}},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := transformationOrder(test.transformations)
+			if test.expectedErr {
+				require.ErrorContains(t, err, "cycle")
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestChainedTransformations(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		config      *models.Config
+		expected    map[string]interface{}
+		expectedErr bool
+	}{
+		{
+			name:   "transformation uses output of another transformation",
+			record: map[string]interface{}{"a": 5, "b": 3},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"doubled": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"$sum", "factor"},
+							Extras: map[string]interface{}{"operation": "multiply", "factor": 2},
+						},
+					},
+					"sum": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"a", "b"},
+							Extras: map[string]interface{}{"operation": "add"},
+						},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"sum": 8.0, "doubled": 16.0},
+			expectedErr: false,
+		},
+		{
+			name:   "transformation uses mapped field",
+			record: map[string]interface{}{"@ID": 12345, "FirstName": "John"},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{"ID": {Field: "id"}},
+				Transformations: map[string]models.Transformation{
+					"label": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"FirstName", "$id"},
+							Extras: map[string]interface{}{"separator": " #"},
+						},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"id": 12345, "label": "John #12345"},
+			expectedErr: false,
+		},
+		{
+			name:   "coalesce uses output of another transformation",
+			record: map[string]interface{}{"FirstName": "John"},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"b_display": {
+						Type:         "concat",
+						Params:       models.Params{Fields: []string{"Nickname"}},
+						FieldOptions: models.FieldOptions{Coalesce: []string{"$z_name"}},
+					},
+					"z_name": {Type: "concat", Params: models.Params{Fields: []string{"FirstName"}}},
+				},
+			},
+			expected:    map[string]interface{}{"b_display": "John", "z_name": "John"},
+			expectedErr: false,
+		},
+		{
+			name:   "steps use previous result",
+			record: map[string]interface{}{"a": 5, "b": 3},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"result": {
+						Steps: []models.Transformation{
+							{
+								Type: "calculate",
+								Params: models.Params{
+									Fields: []string{"a", "b"},
+									Extras: map[string]interface{}{"operation": "add"},
+								},
+							},
+							{
+								Type: "calculate",
+								Params: models.Params{
+									Fields: []string{"$", "divisor"},
+									Extras: map[string]interface{}{"operation": "divide", "divisor": 4},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"result": 2.0},
+			expectedErr: false,
+		},
+		{
+			name:   "step with unknown type",
+			record: map[string]interface{}{"a": 5},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"result": {
+						Steps: []models.Transformation{
+							{Type: "unknown"},
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "cycle",
+			record: map[string]interface{}{},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"a": {Type: "concat", Params: models.Params{Fields: []string{"$b"}}},
+					"b": {Type: "concat", Params: models.Params{Fields: []string{"$a"}}},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := TransformRecord(test.record, test.config, Options{})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewRecordTransformer(t *testing.T) {
+	cfg := &models.Config{
+		Transformations: map[string]models.Transformation{
+			"a": {Type: "concat", Params: models.Params{Fields: []string{"$b"}}},
+			"b": {Type: "concat", Params: models.Params{Fields: []string{"$a"}}},
+		},
+	}
+	_, err := NewRecordTransformer(cfg, Options{})
+	require.EqualError(t, err, "transformations refer to each other in a cycle: a -> b -> a")
+
+	// a cycle is reported before any input is read
+	err = Stream(failingReader{}, &bytes.Buffer{}, cfg)
+	require.EqualError(t, err, "transformations refer to each other in a cycle: a -> b -> a")
+
+	cfg = &models.Config{
+		Transformations: map[string]models.Transformation{
+			"name":  {Type: "concat", Params: models.Params{Fields: []string{"FirstName", "LastName"}, Extras: map[string]interface{}{"separator": " "}}},
+			"upper": {Type: "string", Params: models.Params{Fields: []string{"$name"}, Extras: map[string]interface{}{"operation": "upper"}}},
+		},
+	}
+	transformer, err := NewRecordTransformer(cfg, Options{})
+	require.NoError(t, err)
+	for _, record := range []map[string]interface{}{
+		{"FirstName": "Jane", "LastName": "Doe"},
+		{"FirstName": "John", "LastName": "Smith"},
+	} {
+		actual, err := transformer.Transform(record)
+		require.NoError(t, err)
+		require.Equal(t, strings.ToUpper(actual["name"].(string)), actual["upper"])
+	}
+}
+
+// failingReader returns an error as soon as any input is read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("input was read")
+}
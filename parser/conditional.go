@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"havocai-assignment/models"
+	"strings"
+)
+
+// existsTransformation returns true when every field in fields is present in the record.
+func existsTransformation(record map[string]interface{}, transformation models.Transformation) (interface{}, error) {
+	fields := transformation.Params.Fields
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("exists requires at least one field")
+	}
+
+	for _, field := range fields {
+		if !fieldExists(field, record) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ifTransformation evaluates the "condition" in extras and returns the "then" result when it
+// holds, or the "else" result when it does not. A result is either a literal value, the value of
+// another field written as {"field": "..."}, or the result of another transformation written as
+// {"transformation": {...}}.
+func ifTransformation(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+	extras := transformation.Params.Extras
+	condition, ok := extras["condition"]
+	if !ok {
+		return nil, fmt.Errorf("if requires a condition")
+	}
+
+	holds, err := evaluateCondition(condition, record)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := "else"
+	if holds {
+		branch = "then"
+	}
+
+	result, ok := extras[branch]
+	if !ok {
+		return nil, nil
+	}
+	return resolveResult(result, record, opts)
+}
+
+// evaluateCondition evaluates a predicate against a record. A predicate is an object with a single
+// key naming the check: exists, equals, compare, matches, and, or, not.
+func evaluateCondition(condition interface{}, record map[string]interface{}) (bool, error) {
+	predicate, ok := condition.(map[string]interface{})
+	if !ok || len(predicate) != 1 {
+		return false, fmt.Errorf("condition must be an object with a single key, got %v", condition)
+	}
+
+	for name, args := range predicate {
+		switch name {
+		case "exists":
+			field, ok := args.(string)
+			if !ok {
+				return false, fmt.Errorf("exists requires a field name")
+			}
+			return fieldExists(field, record), nil
+		case "equals":
+			return evaluateComparison(args, record, "==")
+		case "compare":
+			argsMap, ok := args.(map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf("compare requires an object")
+			}
+			op, ok := argsMap["op"].(string)
+			if !ok {
+				return false, fmt.Errorf("compare requires an op")
+			}
+			return evaluateComparison(args, record, op)
+		case "matches":
+			return evaluateMatch(args, record)
+		case "and", "or":
+			conditions, ok := args.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("%v requires a list of conditions", name)
+			}
+			for _, c := range conditions {
+				holds, err := evaluateCondition(c, record)
+				if err != nil {
+					return false, err
+				}
+				// and stops at the first condition that fails, or stops at the first one that holds
+				if holds == (name == "or") {
+					return holds, nil
+				}
+			}
+			return name == "and", nil
+		case "not":
+			holds, err := evaluateCondition(args, record)
+			return !holds, err
+		default:
+			return false, fmt.Errorf("unsupported condition: %v", name)
+		}
+	}
+	return false, nil
+}
+
+// evaluateComparison compares a field with a value using op. Numbers are compared numerically and
+// anything else is compared as text. A field that is missing never satisfies a comparison.
+func evaluateComparison(args interface{}, record map[string]interface{}, op string) (bool, error) {
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("comparison requires an object with a field and value")
+	}
+	field, ok := argsMap["field"].(string)
+	if !ok {
+		return false, fmt.Errorf("comparison requires a field")
+	}
+	expected, ok := argsMap["value"]
+	if !ok {
+		return false, fmt.Errorf("comparison requires a value")
+	}
+
+	actual, found := getFieldValue(field, record, nil)
+	if !found {
+		return false, nil
+	}
+
+	var cmp int
+	actualNum, actualErr := toNumber(actual)
+	expectedNum, expectedErr := toNumber(expected)
+	if actualErr == nil && expectedErr == nil {
+		switch {
+		case actualNum < expectedNum:
+			cmp = -1
+		case actualNum > expectedNum:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(fmt.Sprintf("%v", actual), fmt.Sprintf("%v", expected))
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	default:
+		return false, fmt.Errorf("unsupported comparison: %v", op)
+	}
+}
+
+// evaluateMatch reports whether a field matches a regular expression "pattern".
+func evaluateMatch(args interface{}, record map[string]interface{}) (bool, error) {
+	argsMap, ok := args.(map[string]interface{})
+	if !ok {
+		return false, fmt.Errorf("matches requires an object with a field and pattern")
+	}
+	field, ok := argsMap["field"].(string)
+	if !ok {
+		return false, fmt.Errorf("matches requires a field")
+	}
+	pattern, ok := argsMap["pattern"].(string)
+	if !ok {
+		return false, fmt.Errorf("matches requires a pattern")
+	}
+
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	actual, found := getFieldValue(field, record, nil)
+	if !found {
+		return false, nil
+	}
+	return re.MatchString(fmt.Sprintf("%v", actual)), nil
+}
+
+// resolveResult returns the value of a then or else branch.
+func resolveResult(result interface{}, record map[string]interface{}, opts Options) (interface{}, error) {
+	resultMap, ok := result.(map[string]interface{})
+	if !ok || len(resultMap) != 1 {
+		return result, nil
+	}
+
+	if field, ok := resultMap["field"].(string); ok {
+		val, _ := getFieldValue(field, record, nil)
+		return val, nil
+	}
+
+	if definition, ok := resultMap["transformation"]; ok {
+		// a RecordTransformer decodes branch transformations once, see decodeBranches
+		transformation, ok := definition.(models.Transformation)
+		if !ok {
+			var err error
+			if transformation, err = decodeTransformation(definition); err != nil {
+				return nil, err
+			}
+		}
+		return runTransformation(record, transformation, opts)
+	}
+	return result, nil
+}
+
+// decodeBranches returns a copy of the transformation in which the transformations run by the
+// then and else branches of if transformations, including those in steps and in other branches,
+// are decoded from generic JSON into their struct. A RecordTransformer does this once, so that
+// they are not decoded again for every record. The transformation itself is not modified.
+func decodeBranches(transformation models.Transformation) (models.Transformation, error) {
+	if len(transformation.Steps) > 0 {
+		steps := make([]models.Transformation, len(transformation.Steps))
+		for i, step := range transformation.Steps {
+			decoded, err := decodeBranches(step)
+			if err != nil {
+				return transformation, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			steps[i] = decoded
+		}
+		transformation.Steps = steps
+	}
+	if transformation.Type != "if" {
+		return transformation, nil
+	}
+
+	extras := make(map[string]interface{}, len(transformation.Params.Extras))
+	for key, val := range transformation.Params.Extras {
+		extras[key] = val
+	}
+	for _, branch := range []string{"then", "else"} {
+		result, ok := extras[branch].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		definition, ok := result["transformation"]
+		if !ok {
+			continue
+		}
+
+		nested, ok := definition.(models.Transformation)
+		if !ok {
+			var err error
+			if nested, err = decodeTransformation(definition); err != nil {
+				return transformation, fmt.Errorf("%v: %w", branch, err)
+			}
+		}
+		nested, err := decodeBranches(nested)
+		if err != nil {
+			return transformation, fmt.Errorf("%v: %w", branch, err)
+		}
+		decoded := make(map[string]interface{}, len(result))
+		for key, val := range result {
+			decoded[key] = val
+		}
+		decoded["transformation"] = nested
+		extras[branch] = decoded
+	}
+	transformation.Params.Extras = extras
+	return transformation, nil
+}
+
+// decodeTransformation decodes a transformation that arrives as generic JSON by round tripping it
+// into its struct.
+func decodeTransformation(definition interface{}) (models.Transformation, error) {
+	var transformation models.Transformation
+	data, err := json.Marshal(definition)
+	if err != nil {
+		return transformation, err
+	}
+	if err := json.Unmarshal(data, &transformation); err != nil {
+		return transformation, fmt.Errorf("invalid transformation: %w", err)
+	}
+	return transformation, nil
+}
+
+// fieldExists reports whether a field is present in the record with a non-empty value.
+func fieldExists(field string, record map[string]interface{}) bool {
+	val, found := getFieldValue(field, record, nil)
+	return found && !isEmpty(val)
+}
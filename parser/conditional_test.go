@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExistsTransformation(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		fields      []string
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:        "field present",
+			record:      map[string]interface{}{"DateOfDeath": "2020-01-01"},
+			fields:      []string{"DateOfDeath"},
+			expected:    true,
+			expectedErr: false,
+		},
+		{
+			name:        "field missing",
+			record:      map[string]interface{}{"DateOfBirth": "1985-07-15"},
+			fields:      []string{"DateOfDeath"},
+			expected:    false,
+			expectedErr: false,
+		},
+		{
+			name:        "one of several fields missing",
+			record:      map[string]interface{}{"Street": "123 Havoc Way"},
+			fields:      []string{"Street", "ZipCode"},
+			expected:    false,
+			expectedErr: false,
+		},
+		{
+			name:        "no fields",
+			record:      map[string]interface{}{},
+			fields:      []string{},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformation := models.Transformation{
+				Type:   "exists",
+				Params: models.Params{Fields: test.fields},
+			}
+			actual, err := existsTransformation(test.record, transformation)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIfTransformation(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		extras      map[string]interface{}
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:   "exists",
+			record: map[string]interface{}{"DateOfDeath": "2020-01-01"},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"exists": "DateOfDeath"},
+				"then":      true,
+				"else":      false,
+			},
+			expected:    true,
+			expectedErr: false,
+		},
+		{
+			name:   "equals",
+			record: map[string]interface{}{"Gender": "F"},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"equals": map[string]interface{}{"field": "Gender", "value": "M"}},
+				"then":      "Male",
+				"else":      "Not male",
+			},
+			expected:    "Not male",
+			expectedErr: false,
+		},
+		{
+			name:   "numeric comparison with output field",
+			record: map[string]interface{}{"$age": 12.0},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"compare": map[string]interface{}{"field": "$age", "op": "<", "value": 18}},
+				"then":      "minor",
+				"else":      "adult",
+			},
+			expected:    "minor",
+			expectedErr: false,
+		},
+		{
+			name:   "regex match",
+			record: map[string]interface{}{"Phone": "555-123-4567"},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"matches": map[string]interface{}{"field": "Phone", "pattern": "^555-"}},
+				"then":      "test number",
+			},
+			expected:    "test number",
+			expectedErr: false,
+		},
+		{
+			name:   "and, or and not",
+			record: map[string]interface{}{"Gender": "F", "Age": 30},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"and": []interface{}{
+					map[string]interface{}{"or": []interface{}{
+						map[string]interface{}{"equals": map[string]interface{}{"field": "Gender", "value": "M"}},
+						map[string]interface{}{"equals": map[string]interface{}{"field": "Gender", "value": "F"}},
+					}},
+					map[string]interface{}{"not": map[string]interface{}{"exists": "DateOfDeath"}},
+					map[string]interface{}{"compare": map[string]interface{}{"field": "Age", "op": ">=", "value": 18}},
+				}},
+				"then": "eligible",
+				"else": "not eligible",
+			},
+			expected:    "eligible",
+			expectedErr: false,
+		},
+		{
+			name:   "missing else",
+			record: map[string]interface{}{},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"exists": "DateOfDeath"},
+				"then":      true,
+			},
+			expected:    nil,
+			expectedErr: false,
+		},
+		{
+			name:   "result from another field",
+			record: map[string]interface{}{"FirstName": "John"},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"exists": "PreferredName"},
+				"then":      map[string]interface{}{"field": "PreferredName"},
+				"else":      map[string]interface{}{"field": "FirstName"},
+			},
+			expected:    "John",
+			expectedErr: false,
+		},
+		{
+			name:   "result from another transformation",
+			record: map[string]interface{}{"FirstName": "John", "LastName": "Doe"},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"exists": "LastName"},
+				"then": map[string]interface{}{"transformation": map[string]interface{}{
+					"type": "concat",
+					"params": map[string]interface{}{
+						"fields": []interface{}{"LastName", "FirstName"},
+						"extras": map[string]interface{}{"separator": ", "},
+					},
+				}},
+			},
+			expected:    "Doe, John",
+			expectedErr: false,
+		},
+		{
+			name:   "missing condition",
+			record: map[string]interface{}{},
+			extras: map[string]interface{}{
+				"then": true,
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "unsupported condition",
+			record: map[string]interface{}{},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"contains": "Allergy"},
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "unsupported comparison",
+			record: map[string]interface{}{"Age": 30},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"compare": map[string]interface{}{"field": "Age", "op": "<>", "value": 18}},
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "invalid pattern",
+			record: map[string]interface{}{"Phone": "555-123-4567"},
+			extras: map[string]interface{}{
+				"condition": map[string]interface{}{"matches": map[string]interface{}{"field": "Phone", "pattern": "("}},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformation := models.Transformation{
+				Type:   "if",
+				Params: models.Params{Extras: test.extras},
+			}
+			actual, err := ifTransformation(test.record, transformation, Options{})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDecodeBranches(t *testing.T) {
+	concat := map[string]interface{}{
+		"type":   "concat",
+		"params": map[string]interface{}{"fields": []interface{}{"LastName", "FirstName"}, "extras": map[string]interface{}{"separator": ", "}},
+	}
+	extras := map[string]interface{}{
+		"condition": map[string]interface{}{"exists": "LastName"},
+		"then":      map[string]interface{}{"transformation": concat},
+		"else": map[string]interface{}{"transformation": map[string]interface{}{
+			"type": "if",
+			"params": map[string]interface{}{"extras": map[string]interface{}{
+				"condition": map[string]interface{}{"exists": "FirstName"},
+				"then":      map[string]interface{}{"transformation": concat},
+			}},
+		}},
+	}
+	transformation := models.Transformation{Steps: []models.Transformation{
+		{Type: "if", Params: models.Params{Extras: extras}},
+	}}
+
+	decoded, err := decodeBranches(transformation)
+	require.NoError(t, err)
+
+	// the branches are decoded in the copy and left as generic JSON in the config
+	step := decoded.Steps[0]
+	then := step.Params.Extras["then"].(map[string]interface{})["transformation"]
+	require.Equal(t, models.Transformation{
+		Type:   "concat",
+		Params: models.Params{Fields: []string{"LastName", "FirstName"}, Extras: map[string]interface{}{"separator": ", "}},
+	}, then)
+	nested := step.Params.Extras["else"].(map[string]interface{})["transformation"].(models.Transformation)
+	require.IsType(t, models.Transformation{}, nested.Params.Extras["then"].(map[string]interface{})["transformation"])
+	require.Equal(t, concat, extras["then"].(map[string]interface{})["transformation"])
+
+	actual, err := runTransformation(map[string]interface{}{"FirstName": "John", "LastName": "Doe"}, decoded, Options{})
+	require.NoError(t, err)
+	require.Equal(t, "Doe, John", actual)
+
+	_, err = decodeBranches(models.Transformation{Type: "if", Params: models.Params{Extras: map[string]interface{}{
+		"condition": map[string]interface{}{"exists": "LastName"},
+		"then":      map[string]interface{}{"transformation": map[string]interface{}{"type": 5}},
+	}}})
+	require.ErrorContains(t, err, "then: invalid transformation")
+}
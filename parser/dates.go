@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// embed the time zone database so that timezone and output_timezone work on systems without one
+	_ "time/tzdata"
+)
+
+// dateExtras are the extras that configure a dateParser.
+var dateExtras = []string{"format", "timezone", "output_timezone"}
+
+// dateParser parses dates for the calculate and format transformations using the date extras:
+//   - "format" - the layout of the input dates, or a list of layouts that are tried in order.
+//     Defaults to RFC3339.
+//   - "timezone" - the IANA time zone used for layouts without an offset. Defaults to UTC.
+//   - "output_timezone" - the IANA time zone that dates are converted to before they are
+//     formatted or counted in calendar days. Defaults to the time zone the date was parsed in.
+type dateParser struct {
+	layouts        []string
+	location       *time.Location
+	outputLocation *time.Location
+}
+
+func newDateParser(extras map[string]interface{}) (dateParser, error) {
+	p := dateParser{
+		layouts:  []string{time.RFC3339},
+		location: time.UTC,
+	}
+
+	switch format := extras["format"].(type) {
+	case nil:
+	case string:
+		p.layouts = []string{format}
+	case []string:
+		p.layouts = format
+	case []interface{}:
+		p.layouts = make([]string, len(format))
+		for i, layout := range format {
+			layoutStr, ok := layout.(string)
+			if !ok {
+				return dateParser{}, fmt.Errorf("format must be a string or a list of strings")
+			}
+			p.layouts[i] = layoutStr
+		}
+	default:
+		return dateParser{}, fmt.Errorf("format must be a string or a list of strings")
+	}
+	if len(p.layouts) == 0 {
+		return dateParser{}, fmt.Errorf("format must contain at least one layout")
+	}
+
+	var err error
+	if p.location, err = locationExtra(extras, "timezone"); err != nil {
+		return dateParser{}, err
+	}
+	if p.location == nil {
+		p.location = time.UTC
+	}
+	if p.outputLocation, err = locationExtra(extras, "output_timezone"); err != nil {
+		return dateParser{}, err
+	}
+	return p, nil
+}
+
+// locationExtra loads the time zone named by extras[key], returning nil when it is not set.
+func locationExtra(extras map[string]interface{}, key string) (*time.Location, error) {
+	name, ok := extras[key]
+	if !ok {
+		return nil, nil
+	}
+	nameStr, ok := name.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v must be a string", key)
+	}
+	location, err := time.LoadLocation(nameStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v: %w", key, err)
+	}
+	return location, nil
+}
+
+// parse parses a date using the first layout it matches. Layouts without an offset are read in
+// the parser's time zone.
+func (p dateParser) parse(val interface{}) (time.Time, error) {
+	dateStr, ok := val.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("value %v is not a string", val)
+	}
+
+	dateStr = strings.TrimSpace(dateStr)
+	var firstErr error
+	for _, layout := range p.layouts {
+		date, err := time.ParseInLocation(layout, dateStr, p.location)
+		if err == nil {
+			return date, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if len(p.layouts) == 1 {
+		return time.Time{}, firstErr
+	}
+	return time.Time{}, fmt.Errorf("date %q does not match any of the formats %q", dateStr, p.layouts)
+}
+
+// output converts a date to the output time zone, if there is one.
+func (p dateParser) output(date time.Time) time.Time {
+	if p.outputLocation == nil {
+		return date
+	}
+	return date.In(p.outputLocation)
+}
+
+// dateParsers builds the dateParser for each combination of date extras once, as loading a time
+// zone reads the time zone database. Date extras that a transformation does not set are taken
+// from the config's dates. It is safe for concurrent use.
+type dateParsers struct {
+	defaults map[string]interface{}
+
+	mu      sync.Mutex
+	parsers map[string]dateParser
+}
+
+func newDateParsers(defaults map[string]interface{}) *dateParsers {
+	return &dateParsers{defaults: defaults, parsers: make(map[string]dateParser)}
+}
+
+// get returns the dateParser for the date extras of a transformation.
+func (d *dateParsers) get(extras map[string]interface{}) (dateParser, error) {
+	settings := make(map[string]interface{}, len(dateExtras))
+	for _, key := range dateExtras {
+		if val, ok := extras[key]; ok {
+			settings[key] = val
+		} else if val, ok := d.defaults[key]; ok {
+			settings[key] = val
+		}
+	}
+	key := fmt.Sprintf("%#v|%#v|%#v", settings["format"], settings["timezone"], settings["output_timezone"])
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p, ok := d.parsers[key]; ok {
+		return p, nil
+	}
+	p, err := newDateParser(settings)
+	if err != nil {
+		return dateParser{}, err
+	}
+	d.parsers[key] = p
+	return p, nil
+}
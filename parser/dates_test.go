@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDateParser(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		extras      map[string]interface{}
+		input       interface{}
+		expected    time.Time
+		expectedErr bool
+	}{
+		{
+			name:        "RFC3339 by default",
+			extras:      map[string]interface{}{},
+			input:       "2025-01-29T08:30:00-05:00",
+			expected:    time.Date(2025, time.January, 29, 13, 30, 0, 0, time.UTC),
+			expectedErr: false,
+		},
+		{
+			name:        "single format",
+			extras:      map[string]interface{}{"format": "2006-01-02"},
+			input:       "2025-01-29",
+			expected:    time.Date(2025, time.January, 29, 0, 0, 0, 0, time.UTC),
+			expectedErr: false,
+		},
+		{
+			name:        "second of several formats",
+			extras:      map[string]interface{}{"format": []interface{}{"2006-01-02", "01/02/2006", time.RFC3339}},
+			input:       "01/29/2025",
+			expected:    time.Date(2025, time.January, 29, 0, 0, 0, 0, time.UTC),
+			expectedErr: false,
+		},
+		{
+			name:        "date without offset read in timezone",
+			extras:      map[string]interface{}{"format": "2006-01-02 15:04", "timezone": "America/New_York"},
+			input:       "2025-01-29 08:30",
+			expected:    time.Date(2025, time.January, 29, 8, 30, 0, 0, newYork),
+			expectedErr: false,
+		},
+		{
+			name:        "offset in date takes precedence over timezone",
+			extras:      map[string]interface{}{"timezone": "America/New_York"},
+			input:       "2025-01-29T08:30:00Z",
+			expected:    time.Date(2025, time.January, 29, 8, 30, 0, 0, time.UTC),
+			expectedErr: false,
+		},
+		{
+			name:        "date matches none of the formats",
+			extras:      map[string]interface{}{"format": []interface{}{"2006-01-02", "01/02/2006"}},
+			input:       "Jan 29 2025",
+			expectedErr: true,
+		},
+		{
+			name:        "date is not a string",
+			extras:      map[string]interface{}{},
+			input:       20250129,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid format",
+			extras:      map[string]interface{}{"format": []interface{}{"2006-01-02", 1}},
+			input:       "2025-01-29",
+			expectedErr: true,
+		},
+		{
+			name:        "empty list of formats",
+			extras:      map[string]interface{}{"format": []interface{}{}},
+			input:       "2025-01-29",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown timezone",
+			extras:      map[string]interface{}{"timezone": "Mars/Olympus_Mons"},
+			input:       "2025-01-29T08:30:00Z",
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dates, err := newDateParser(test.extras)
+			var actual time.Time
+			if err == nil {
+				actual, err = dates.parse(test.input)
+			}
+
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.True(t, test.expected.Equal(actual), "expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDateParserOutput(t *testing.T) {
+	dates, err := newDateParser(map[string]interface{}{"output_timezone": "Asia/Tokyo"})
+	require.NoError(t, err)
+
+	date, err := dates.parse("2025-01-29T20:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, "2025-01-30T05:00:00+09:00", dates.output(date).Format(time.RFC3339))
+
+	dates, err = newDateParser(map[string]interface{}{})
+	require.NoError(t, err)
+	require.Equal(t, date, dates.output(date))
+}
+
+func TestDateParsers(t *testing.T) {
+	parsers := newDateParsers(map[string]interface{}{
+		"format":   []interface{}{"2006-01-02", "01/02/2006"},
+		"timezone": "America/New_York",
+	})
+
+	// extras that are not set are taken from the defaults
+	dates, err := parsers.get(map[string]interface{}{"operation": "time_difference"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2006-01-02", "01/02/2006"}, dates.layouts)
+	require.Equal(t, "America/New_York", dates.location.String())
+	require.Nil(t, dates.outputLocation)
+
+	// extras that are set take precedence over the defaults
+	dates, err = parsers.get(map[string]interface{}{"format": "2006-01-02 15:04", "output_timezone": "UTC"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"2006-01-02 15:04"}, dates.layouts)
+	require.Equal(t, "America/New_York", dates.location.String())
+	require.Equal(t, time.UTC, dates.outputLocation)
+
+	// each combination of date extras is only built once
+	_, err = parsers.get(map[string]interface{}{"unit": "years"})
+	require.NoError(t, err)
+	require.Len(t, parsers.parsers, 2)
+
+	_, err = parsers.get(map[string]interface{}{"timezone": "America/Nowhere"})
+	require.Error(t, err)
+}
@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"havocai-assignment/models"
+	"io"
+	"strings"
+)
+
+// textKey holds the character data of an element that also has attributes or child elements.
+const textKey = "#text"
+
+// RecordDecoder reads records one at a time from an XML stream, so only the record that is
+// currently being built is held in memory. Each record is built as a tree: elements that contain
+// other elements become nested maps, so groupings like <Address><Street> are kept as
+// {"Address": {"Street": ...}}, and repeated sibling elements are collected into slices in
+// document order. Names in a namespace are stored as "prefix:Name", using the prefixes bound in
+// the config's namespaces where possible.
+type RecordDecoder struct {
+	decoder          *xml.Decoder
+	recordPath       []string
+	forceArray       map[string]bool
+	keepDeclarations bool
+	scope            *namespaceScope
+	maxDepth         int
+	values           *valueParser
+	// keepEmpty holds the names of elements that are kept as "" when they are empty, as a
+	// null-if-empty mapping reads them
+	keepEmpty map[string]bool
+	// err is returned by Next when the decoder could not be set up from the config
+	err error
+	// records is the number of records read so far, used as the index of the next record
+	records int
+	// recordErr is the first value of the current record that did not match its type hint
+	recordErr error
+
+	// names of the open elements outside of a record, used to find where records start
+	path []string
+	// stack of elements that are currently open within a record; the first entry is the record itself
+	stack []*element
+}
+
+// NewRecordDecoder creates a RecordDecoder that reads from r. The config may be nil, in which
+// case every child of the document root is a record.
+func NewRecordDecoder(r io.Reader, cfg *models.Config) *RecordDecoder {
+	d := &RecordDecoder{
+		decoder:    xml.NewDecoder(r),
+		recordPath: defaultRecordPath,
+		forceArray: make(map[string]bool),
+		keepEmpty:  make(map[string]bool),
+	}
+
+	var namespaces map[string]string
+	var types models.Types
+	var dates map[string]interface{}
+	if cfg != nil {
+		if cfg.RecordPath != "" {
+			d.recordPath = splitRecordPath(cfg.RecordPath)
+		}
+		for _, name := range cfg.ForceArray {
+			d.forceArray[name] = true
+		}
+		namespaces = cfg.Namespaces
+		d.keepDeclarations = cfg.KeepNamespaceDeclarations
+		types = cfg.Types
+		types.Hints = mappingHints(cfg)
+		dates = cfg.Dates
+		for _, name := range mappedNames(cfg.Mappings, "null-if-empty") {
+			d.keepEmpty[name] = true
+		}
+	}
+	d.scope = newNamespaceScope(namespaces)
+	d.values, d.err = newValueParser(types, dates)
+	return d
+}
+
+// SetMaxDepth limits how deeply elements may be nested, counting the document root as depth 1.
+// Next returns an error wrapping ErrMaxDepth when the limit is exceeded. A limit of 0 means no limit.
+func (d *RecordDecoder) SetMaxDepth(depth int) {
+	d.maxDepth = depth
+}
+
+// Next returns the next record in the document, or io.EOF once there are no more records.
+// A record with a value that does not match its type hint is read to its end and returned as a
+// *RecordError wrapping ErrTypeHint, so the following call carries on with the next record.
+func (d *RecordDecoder) Next() (map[string]interface{}, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	for {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if d.maxDepth > 0 && d.depth()+1 > d.maxDepth {
+				return nil, fmt.Errorf("element %v: %w", t.Name.Local, ErrMaxDepth)
+			}
+
+			d.scope.push(t.Attr)
+			name := d.scope.elementName(t.Name)
+
+			// outside of a record, only an element matching the record path starts a new record
+			if len(d.stack) == 0 {
+				d.path = append(d.path, name)
+				if !matchRecordPath(d.path, d.recordPath) {
+					continue
+				}
+			}
+
+			el := newElement(name)
+			// need to handle scenario where a start element has attributes, which are kept apart from
+			// child elements of the same name by their prefix
+			for _, attr := range t.Attr {
+				if isNamespaceDeclaration(attr) && !d.keepDeclarations {
+					continue
+				}
+				name := attrPrefix + d.scope.attrName(attr.Name)
+				val, err := d.values.parse(name, attr.Value)
+				if err != nil {
+					d.fail(err)
+					continue
+				}
+				el.children[name] = val
+			}
+			d.stack = append(d.stack, el)
+		case xml.EndElement:
+			d.scope.pop()
+			if len(d.stack) == 0 {
+				d.path = d.path[:len(d.path)-1]
+				continue
+			}
+
+			el := d.stack[len(d.stack)-1]
+			d.stack = d.stack[:len(d.stack)-1]
+
+			// if the stack is empty, we are at the end of a record
+			if len(d.stack) == 0 {
+				d.path = d.path[:len(d.path)-1]
+				return d.finish(el)
+			}
+
+			value, ok, err := el.value(d.values, d.keepEmpty[el.name])
+			if err != nil {
+				d.fail(err)
+			}
+			if ok {
+				d.stack[len(d.stack)-1].add(el.name, value, d.forceArray[el.name])
+			}
+		case xml.CharData:
+			// when we encounter CharData, store the character data on the element it belongs to
+			if len(d.stack) > 0 {
+				d.stack[len(d.stack)-1].text.Write(t)
+			}
+		}
+	}
+}
+
+// fail keeps the first error of the current record, which is returned once the record has been
+// read to its end.
+func (d *RecordDecoder) fail(err error) {
+	if d.recordErr == nil {
+		d.recordErr = err
+	}
+}
+
+// finish returns the record that has just been read, or the first error found while reading it.
+func (d *RecordDecoder) finish(el *element) (map[string]interface{}, error) {
+	index := d.records
+	d.records++
+
+	record, err := el.record(d.values)
+	d.fail(err)
+	if err := d.recordErr; err != nil {
+		d.recordErr = nil
+		return nil, &RecordError{Index: index, Err: err}
+	}
+	return record, nil
+}
+
+// depth returns the number of elements that are currently open. The record element is the last
+// entry of path as well as the first entry of stack, so it is only counted once.
+func (d *RecordDecoder) depth() int {
+	if len(d.stack) == 0 {
+		return len(d.path)
+	}
+	return len(d.path) + len(d.stack) - 1
+}
+
+// defaultRecordPath treats every child of the document root as a record.
+var defaultRecordPath = []string{"*", "*"}
+
+// splitRecordPath splits a record path such as "Export/Patients/Patient" into element names.
+func splitRecordPath(recordPath string) []string {
+	return strings.Split(strings.Trim(recordPath, "/"), "/")
+}
+
+// matchRecordPath reports whether the path of open elements matches the record path exactly.
+// A "*" in the record path matches any element name.
+func matchRecordPath(path []string, recordPath []string) bool {
+	if len(path) != len(recordPath) {
+		return false
+	}
+	for i, name := range recordPath {
+		if name != "*" && name != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// element is an XML element that is still being built while its children are parsed.
+type element struct {
+	name     string
+	children map[string]interface{}
+	text     strings.Builder
+}
+
+func newElement(name string) *element {
+	return &element{
+		name:     name,
+		children: make(map[string]interface{}),
+	}
+}
+
+// add stores the value of a child element. Repeated children are collected into a slice, and
+// children listed in force_array are always stored as a slice even when they appear only once.
+func (el *element) add(name string, value interface{}, asArray bool) {
+	existing, ok := el.children[name]
+	if !ok {
+		if asArray {
+			value = []interface{}{value}
+		}
+		el.children[name] = value
+		return
+	}
+
+	if list, isList := existing.([]interface{}); isList {
+		el.children[name] = append(list, value)
+		return
+	}
+	el.children[name] = []interface{}{existing, value}
+}
+
+// value returns the value an element contributes to its parent. Elements with only text become
+// scalar values, elements with attributes or children become maps, and empty elements are dropped
+// unless keepEmpty is set, in which case they become "". The text of an element is typed by its
+// own name, including when it is kept under #text.
+func (el *element) value(values *valueParser, keepEmpty bool) (interface{}, bool, error) {
+	content := strings.TrimSpace(el.text.String())
+	if len(el.children) == 0 {
+		if content == "" {
+			return "", keepEmpty, nil
+		}
+		val, err := values.parse(el.name, content)
+		return val, err == nil, err
+	}
+
+	if content != "" {
+		val, err := values.parse(el.name, content)
+		if err != nil {
+			return nil, false, err
+		}
+		el.children[textKey] = val
+	}
+	return el.children, true, nil
+}
+
+// record returns the element as a record, which is always a map.
+func (el *element) record(values *valueParser) (map[string]interface{}, error) {
+	if content := strings.TrimSpace(el.text.String()); content != "" {
+		val, err := values.parse(el.name, content)
+		if err != nil {
+			return nil, err
+		}
+		el.children[textKey] = val
+	}
+	return el.children, nil
+}
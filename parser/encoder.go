@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RecordWriter writes transformed records to an output as they are produced.
+type RecordWriter interface {
+	// Write appends a record to the output.
+	Write(record map[string]interface{}) error
+	// Close finishes the output. It does not close the underlying writer.
+	Close() error
+}
+
+// JSONWriter writes records as they are produced into a single JSON document of the form
+// {"<root>": [record, ...]}, formatted the same way as json.MarshalIndent with two spaces.
+type JSONWriter struct {
+	w       io.Writer
+	records int
+}
+
+// NewJSONWriter starts a JSON document on w with the records wrapped in the given root name.
+func NewJSONWriter(w io.Writer, root string) (*JSONWriter, error) {
+	rootName, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(w, "{\n  %s: [", rootName); err != nil {
+		return nil, fmt.Errorf("error writing JSON output: %w", err)
+	}
+	return &JSONWriter{w: w}, nil
+}
+
+// Write appends a record to the document.
+func (jw *JSONWriter) Write(record map[string]interface{}) error {
+	data, err := json.MarshalIndent(record, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n    "
+	if jw.records == 0 {
+		separator = "\n    "
+	}
+	jw.records++
+
+	if _, err := io.WriteString(jw.w, separator); err != nil {
+		return fmt.Errorf("error writing JSON output: %w", err)
+	}
+	if _, err := jw.w.Write(data); err != nil {
+		return fmt.Errorf("error writing JSON output: %w", err)
+	}
+	return nil
+}
+
+// Close ends the document. It does not close the underlying writer.
+func (jw *JSONWriter) Close() error {
+	end := "]\n}\n"
+	if jw.records > 0 {
+		end = "\n  ]\n}\n"
+	}
+
+	if _, err := io.WriteString(jw.w, end); err != nil {
+		return fmt.Errorf("error writing JSON output: %w", err)
+	}
+	return nil
+}
+
+// JSONLinesWriter writes each record as compact JSON on its own line, without a root element.
+type JSONLinesWriter struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLinesWriter creates a JSONLinesWriter that writes to w.
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	return &JSONLinesWriter{encoder: json.NewEncoder(w)}
+}
+
+// Write appends a record as a single line.
+func (jw *JSONLinesWriter) Write(record map[string]interface{}) error {
+	if err := jw.encoder.Encode(record); err != nil {
+		return fmt.Errorf("error writing JSON output: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing, as every line is complete once it has been written.
+func (jw *JSONLinesWriter) Close() error {
+	return nil
+}
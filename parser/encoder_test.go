@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		records []map[string]interface{}
+	}{
+		{
+			name:    "no records",
+			root:    "patients",
+			records: []map[string]interface{}{},
+		},
+		{
+			name: "single record",
+			root: "patients",
+			records: []map[string]interface{}{
+				{"id": 12345, "name": "John Doe"},
+			},
+		},
+		{
+			name: "multiple nested records",
+			root: "patients",
+			records: []map[string]interface{}{
+				{"id": 12345, "address": map[string]interface{}{"street": "123 Havoc Way"}},
+				{"id": 67890, "phones": []interface{}{"123-456-7890", "555-555-5555"}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewJSONWriter(&buf, test.root)
+			require.NoError(t, err)
+			for _, record := range test.records {
+				require.NoError(t, writer.Write(record))
+			}
+			require.NoError(t, writer.Close())
+
+			// output should match marshalling the whole document at once
+			expected, err := json.MarshalIndent(map[string]interface{}{test.root: test.records}, "", "  ")
+			require.NoError(t, err)
+			require.Equal(t, string(expected)+"\n", buf.String())
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMaxDepth is returned when elements are nested deeper than the decoder allows.
+var ErrMaxDepth = errors.New("maximum element depth exceeded")
+
+// ErrMaxRecords is returned when the input contains more records than a stream allows.
+var ErrMaxRecords = errors.New("maximum number of records exceeded")
+
+// ErrRequiredField is returned when a required output field has no value.
+var ErrRequiredField = errors.New("required field has no value")
+
+// ErrOutputType is returned when a value cannot be converted to a field's output_type.
+var ErrOutputType = errors.New("value cannot be converted to output_type")
+
+// ErrTypeHint is returned when the text of an element or attribute does not match its type hint.
+var ErrTypeHint = errors.New("value does not match type hint")
+
+// FieldError is returned when a single output field of a record cannot be produced.
+type FieldError struct {
+	// Field is the name of the output field
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %q: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// RecordError is returned when a single record cannot be converted.
+type RecordError struct {
+	// Index is the position of the record in the input, starting at 0
+	Index int
+	Err   error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
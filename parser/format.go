@@ -0,0 +1,242 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/models"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// formatTransformation formats the value of a single field according to the "mode" in extras:
+// date, number, currency, pad or phone.
+func formatTransformation(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+	extras := transformation.Params.Extras
+	mode, ok := extras["mode"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid mode")
+	}
+
+	fields := transformation.Params.Fields
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("format requires exactly one field")
+	}
+
+	val, found := getFieldValue(fields[0], record, extras)
+	if !found {
+		return nil, fmt.Errorf("field %v not found in XML or extras", fields[0])
+	}
+
+	switch mode {
+	case "date":
+		return formatDate(val, extras, opts)
+	case "number":
+		return formatNumber(val, extras)
+	case "currency":
+		return formatCurrency(val, extras)
+	case "pad":
+		return formatPad(val, extras)
+	case "phone":
+		return formatPhone(val, extras)
+	default:
+		return nil, fmt.Errorf("unsupported format mode: %v", mode)
+	}
+}
+
+// formatDate re-lays out a date from the input "format" (RFC3339 by default) to "output_format",
+// in the "output_timezone" when one is set.
+func formatDate(val interface{}, extras map[string]interface{}, opts Options) (string, error) {
+	outputFormat, ok := extras["output_format"].(string)
+	if !ok {
+		return "", fmt.Errorf("date format requires an output_format")
+	}
+
+	dates, err := opts.dateParser(extras)
+	if err != nil {
+		return "", err
+	}
+
+	date, err := dates.parse(val)
+	if err != nil {
+		return "", err
+	}
+	return dates.output(date).Format(outputFormat), nil
+}
+
+// formatNumber formats a number with an optional decimal_precision, thousands_separator and
+// decimal_separator. Without a decimal_precision, the number keeps all of its decimal places.
+func formatNumber(val interface{}, extras map[string]interface{}) (string, error) {
+	number, err := toNumber(val)
+	if err != nil {
+		return "", err
+	}
+
+	precision := -1
+	if p, ok, err := intExtra(extras, "decimal_precision"); err != nil {
+		return "", err
+	} else if ok {
+		precision = p
+	}
+
+	thousands, _ := extras["thousands_separator"].(string)
+	decimal := "."
+	if d, ok := extras["decimal_separator"].(string); ok {
+		decimal = d
+	}
+	return groupNumber(number, precision, thousands, decimal), nil
+}
+
+// formatCurrency formats a number as money with two decimal places, e.g. 1000.5 -> $1000.50.
+// The symbol defaults to "$" and a thousands_separator can be added.
+func formatCurrency(val interface{}, extras map[string]interface{}) (string, error) {
+	number, err := toNumber(val)
+	if err != nil {
+		return "", err
+	}
+
+	symbol := "$"
+	if s, ok := extras["symbol"].(string); ok {
+		symbol = s
+	}
+	thousands, _ := extras["thousands_separator"].(string)
+
+	formatted := groupNumber(math.Abs(number), 2, thousands, ".")
+	if number < 0 && formatted != groupNumber(0, 2, thousands, ".") {
+		return "-" + symbol + formatted, nil
+	}
+	return symbol + formatted, nil
+}
+
+// formatPad pads a value to "width" characters with the "fill" character, "0" by default.
+// Padding is added on the left unless "align" is "left".
+func formatPad(val interface{}, extras map[string]interface{}) (string, error) {
+	width, ok, err := intExtra(extras, "width")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("pad format requires a width")
+	}
+
+	fill := "0"
+	if f, ok := extras["fill"].(string); ok {
+		if len([]rune(f)) != 1 {
+			return "", fmt.Errorf("fill must be a single character")
+		}
+		fill = f
+	}
+
+	strVal := fmt.Sprintf("%v", val)
+	padding := width - len([]rune(strVal))
+	if padding <= 0 {
+		return strVal, nil
+	}
+
+	if align, _ := extras["align"].(string); align == "left" {
+		return strVal + strings.Repeat(fill, padding), nil
+	}
+	return strings.Repeat(fill, padding) + strVal, nil
+}
+
+// formatPhone places the digits of a value into a "pattern" where each # is replaced by the next
+// digit, e.g. "(###) ###-####". The value must have exactly as many digits as the pattern.
+func formatPhone(val interface{}, extras map[string]interface{}) (string, error) {
+	pattern, ok := extras["pattern"].(string)
+	if !ok {
+		return "", fmt.Errorf("phone format requires a pattern")
+	}
+
+	var digits []rune
+	for _, r := range fmt.Sprintf("%v", val) {
+		if unicode.IsDigit(r) {
+			digits = append(digits, r)
+		}
+	}
+
+	if expected := strings.Count(pattern, "#"); len(digits) != expected {
+		return "", fmt.Errorf("value %v has %d digits, pattern %q requires %d", val, len(digits), pattern, expected)
+	}
+
+	var builder strings.Builder
+	next := 0
+	for _, r := range pattern {
+		if r == '#' {
+			builder.WriteRune(digits[next])
+			next++
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String(), nil
+}
+
+// groupNumber formats a number with the given precision (-1 for as many places as needed) and
+// inserts the thousands separator between every group of three digits.
+func groupNumber(number float64, precision int, thousands string, decimal string) string {
+	formatted := strconv.FormatFloat(number, 'f', precision, 64)
+
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign = "-"
+		formatted = formatted[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(formatted, ".")
+	if thousands != "" {
+		var groups []string
+		for len(intPart) > 3 {
+			groups = append([]string{intPart[len(intPart)-3:]}, groups...)
+			intPart = intPart[:len(intPart)-3]
+		}
+		intPart = strings.Join(append([]string{intPart}, groups...), thousands)
+	}
+
+	if hasFrac {
+		return sign + intPart + decimal + fracPart
+	}
+	return sign + intPart
+}
+
+// toNumber converts a parsed value or a numeric string to a float64.
+func toNumber(val interface{}) (float64, error) {
+	if strVal, ok := val.(string); ok {
+		number, err := strconv.ParseFloat(strings.TrimSpace(strVal), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %v is not a number", val)
+		}
+		return number, nil
+	}
+
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Convert(reflect.TypeOf(float64(0))).Float(), nil
+	default:
+		return 0, fmt.Errorf("value %v is not a number", val)
+	}
+}
+
+// intExtra reads a whole number from extras. Numbers in a JSON config are decoded as float64,
+// so both int and whole float64 values are accepted.
+func intExtra(extras map[string]interface{}, key string) (int, bool, error) {
+	val, ok := extras[key]
+	if !ok {
+		return 0, false, nil
+	}
+
+	switch v := val.(type) {
+	case int:
+		return v, true, nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false, fmt.Errorf("%v must be a whole number", key)
+		}
+		return int(v), true, nil
+	default:
+		return 0, false, fmt.Errorf("%v must be a number", key)
+	}
+}
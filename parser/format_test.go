@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatTransformation(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		extras      map[string]interface{}
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:   "date to new layout",
+			record: map[string]interface{}{"Value": "1985-07-15"},
+			extras: map[string]interface{}{
+				"mode":          "date",
+				"format":        "2006-01-02",
+				"output_format": "02-01-2006",
+			},
+			expected:    "15-07-1985",
+			expectedErr: false,
+		},
+		{
+			name:   "date without output format",
+			record: map[string]interface{}{"Value": "1985-07-15"},
+			extras: map[string]interface{}{
+				"mode":   "date",
+				"format": "2006-01-02",
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "date not in format",
+			record: map[string]interface{}{"Value": "07/15/1985"},
+			extras: map[string]interface{}{
+				"mode":          "date",
+				"format":        "2006-01-02",
+				"output_format": "02-01-2006",
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "date in one of several layouts",
+			record: map[string]interface{}{"Value": "07/15/1985"},
+			extras: map[string]interface{}{
+				"mode":          "date",
+				"format":        []interface{}{"2006-01-02", "01/02/2006"},
+				"output_format": "2006-01-02",
+			},
+			expected:    "1985-07-15",
+			expectedErr: false,
+		},
+		{
+			name:   "date in output timezone",
+			record: map[string]interface{}{"Value": "2025-01-29 20:00"},
+			extras: map[string]interface{}{
+				"mode":            "date",
+				"format":          "2006-01-02 15:04",
+				"timezone":        "America/New_York",
+				"output_timezone": "UTC",
+				"output_format":   time.RFC3339,
+			},
+			expected:    "2025-01-30T01:00:00Z",
+			expectedErr: false,
+		},
+		{
+			name:   "number with precision and thousands separator",
+			record: map[string]interface{}{"Value": 1234567.891},
+			extras: map[string]interface{}{
+				"mode":                "number",
+				"decimal_precision":   2.0,
+				"thousands_separator": ",",
+			},
+			expected:    "1,234,567.89",
+			expectedErr: false,
+		},
+		{
+			name:   "number with european separators",
+			record: map[string]interface{}{"Value": "-1234.5"},
+			extras: map[string]interface{}{
+				"mode":                "number",
+				"decimal_precision":   2,
+				"thousands_separator": ".",
+				"decimal_separator":   ",",
+			},
+			expected:    "-1.234,50",
+			expectedErr: false,
+		},
+		{
+			name:   "number without precision",
+			record: map[string]interface{}{"Value": 1000.125},
+			extras: map[string]interface{}{
+				"mode": "number",
+			},
+			expected:    "1000.125",
+			expectedErr: false,
+		},
+		{
+			name:   "number with fractional precision",
+			record: map[string]interface{}{"Value": 1000.125},
+			extras: map[string]interface{}{
+				"mode":              "number",
+				"decimal_precision": 1.5,
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "number from text",
+			record: map[string]interface{}{"Value": "abc"},
+			extras: map[string]interface{}{
+				"mode": "number",
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "currency",
+			record: map[string]interface{}{"Value": 1000.5},
+			extras: map[string]interface{}{
+				"mode": "currency",
+			},
+			expected:    "$1000.50",
+			expectedErr: false,
+		},
+		{
+			name:   "negative currency with symbol and thousands separator",
+			record: map[string]interface{}{"Value": -1234567},
+			extras: map[string]interface{}{
+				"mode":                "currency",
+				"symbol":              "€",
+				"thousands_separator": ",",
+			},
+			expected:    "-€1,234,567.00",
+			expectedErr: false,
+		},
+		{
+			name:   "zero padding",
+			record: map[string]interface{}{"Value": 23},
+			extras: map[string]interface{}{
+				"mode":  "pad",
+				"width": 5.0,
+			},
+			expected:    "00023",
+			expectedErr: false,
+		},
+		{
+			name:   "left aligned padding with fill character",
+			record: map[string]interface{}{"Value": "AB"},
+			extras: map[string]interface{}{
+				"mode":  "pad",
+				"width": 4,
+				"fill":  "*",
+				"align": "left",
+			},
+			expected:    "AB**",
+			expectedErr: false,
+		},
+		{
+			name:   "value longer than width",
+			record: map[string]interface{}{"Value": 123456},
+			extras: map[string]interface{}{
+				"mode":  "pad",
+				"width": 3,
+			},
+			expected:    "123456",
+			expectedErr: false,
+		},
+		{
+			name:   "padding without width",
+			record: map[string]interface{}{"Value": 23},
+			extras: map[string]interface{}{
+				"mode": "pad",
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "phone",
+			record: map[string]interface{}{"Value": "888-555-1234"},
+			extras: map[string]interface{}{
+				"mode":    "phone",
+				"pattern": "(###) ###-####",
+			},
+			expected:    "(888) 555-1234",
+			expectedErr: false,
+		},
+		{
+			name:   "phone with wrong number of digits",
+			record: map[string]interface{}{"Value": "555-1234"},
+			extras: map[string]interface{}{
+				"mode":    "phone",
+				"pattern": "(###) ###-####",
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "unsupported mode",
+			record: map[string]interface{}{"Value": "abc"},
+			extras: map[string]interface{}{
+				"mode": "roman",
+			},
+			expectedErr: true,
+		},
+		{
+			name:   "field not in record",
+			record: map[string]interface{}{},
+			extras: map[string]interface{}{
+				"mode": "number",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformation := models.Transformation{
+				Type: "format",
+				Params: models.Params{
+					Fields: []string{"Value"},
+					Extras: test.extras,
+				},
+			}
+			actual, err := formatTransformation(test.record, transformation, Options{})
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -2,20 +2,23 @@ package parser
 
 import (
 	"fmt"
+	"havocai-assignment/internal/maputil"
 	"math"
 	"reflect"
+	"regexp"
 	"strconv"
-	"time"
+	"strings"
+	"sync"
 )
 
-func toFloat64(input interface{}, format string) (float64, error) {
+func toFloat64(input interface{}, dates dateParser) (float64, error) {
 	v := reflect.ValueOf(input)
 	v = reflect.Indirect(v)
 	floatType := reflect.TypeOf(float64(0))
 
 	if v.Kind() == reflect.String {
 		dateStr := v.String()
-		if parsedDate, err := parseDateString(dateStr, format); err == nil {
+		if parsedDate, err := parseDateString(dateStr, dates); err == nil {
 			return parsedDate, nil
 		}
 
@@ -32,8 +35,8 @@ func toFloat64(input interface{}, format string) (float64, error) {
 	return floatVal.Float(), nil
 }
 
-func parseDateString(dateStr string, format string) (float64, error) {
-	date, err := time.Parse(format, dateStr)
+func parseDateString(dateStr string, dates dateParser) (float64, error) {
+	date, err := dates.parse(dateStr)
 	if err != nil {
 		return 0, fmt.Errorf("error converting datestring to float64: %v", err)
 	}
@@ -41,7 +44,16 @@ func parseDateString(dateStr string, format string) (float64, error) {
 }
 
 func getFieldValue(field string, record map[string]interface{}, extras map[string]interface{}) (interface{}, bool) {
-	if val, found := record[field]; found {
+	if strings.HasPrefix(field, outputRefPrefix) {
+		// output fields are only ever stored at the top level of the record
+		if val, found := record[field]; found {
+			return val, true
+		}
+	} else if isPath(field) {
+		if val, found := resolvePath(field, record); found {
+			return val, true
+		}
+	} else if val, found := findField(field, record); found {
 		return val, true
 	}
 	if val, found := extras[field]; found {
@@ -50,6 +62,43 @@ func getFieldValue(field string, record map[string]interface{}, extras map[strin
 	return nil, false
 }
 
+// isEmpty reports whether a value counts as missing: nil or an empty string.
+func isEmpty(val interface{}) bool {
+	return val == nil || val == ""
+}
+
+// findField looks for a bare field name at the top level of a record first, then searches nested
+// elements level by level so that "Street" still finds Address/Street. At each level an element
+// with that name is preferred over an attribute. Repeated elements are searched in document order.
+func findField(field string, record map[string]interface{}) (interface{}, bool) {
+	queue := []map[string]interface{}{record}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if val, found := current[field]; found {
+			return val, true
+		}
+		if val, found := current[attrPrefix+field]; found {
+			return val, true
+		}
+		// sort keys so that the same field is always found when it appears in more than one place
+		for _, key := range maputil.SortedKeys(current) {
+			switch nested := current[key].(type) {
+			case map[string]interface{}:
+				queue = append(queue, nested)
+			case []interface{}:
+				for _, item := range nested {
+					if nestedItem, ok := item.(map[string]interface{}); ok {
+						queue = append(queue, nestedItem)
+					}
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
 func addValues(values []float64) float64 {
 	sum := 0.0
 	for _, val := range values {
@@ -94,3 +143,25 @@ func modValues(values []float64) (float64, error) {
 	}
 	return math.Mod(values[0], values[1]), nil
 }
+
+var (
+	regexCacheMu sync.Mutex
+	regexCache   = make(map[string]*regexp.Regexp)
+)
+
+// compileRegex compiles a pattern from the config once and reuses it for every record.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCacheMu.Lock()
+	defer regexCacheMu.Unlock()
+
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	regexCache[pattern] = re
+	return re, nil
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -53,7 +54,7 @@ func TestToFloat(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual, err := toFloat64(test.input, test.format)
+			actual, err := toFloat64(test.input, dateParser{layouts: []string{test.format}, location: time.UTC})
 			if test.expectedErr {
 				require.Error(t, err)
 			} else {
@@ -89,7 +90,7 @@ func TestParseDateString(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual, err := parseDateString(test.dateStr, test.format)
+			actual, err := parseDateString(test.dateStr, dateParser{layouts: []string{test.format}, location: time.UTC})
 			if test.expectedErr {
 				require.Error(t, err)
 			} else {
@@ -129,6 +130,55 @@ func TestGetFieldValue(t *testing.T) {
 			expected:   30,
 			expectedOk: true,
 		},
+		{
+			name:  "field exists in nested element",
+			field: "Street",
+			record: map[string]interface{}{
+				"FirstName": "John",
+				"Address": map[string]interface{}{
+					"Street": "123 Havoc Way",
+				},
+			},
+			extras:     map[string]interface{}{},
+			expected:   "123 Havoc Way",
+			expectedOk: true,
+		},
+		{
+			name:  "top level field preferred over nested element",
+			field: "ID",
+			record: map[string]interface{}{
+				"ID": 12345,
+				"Provider": map[string]interface{}{
+					"ID": 999,
+				},
+			},
+			extras:     map[string]interface{}{},
+			expected:   12345,
+			expectedOk: true,
+		},
+		{
+			name:  "bare name finds attribute",
+			field: "ID",
+			record: map[string]interface{}{
+				"@ID": 12345,
+			},
+			extras:     map[string]interface{}{},
+			expected:   12345,
+			expectedOk: true,
+		},
+		{
+			name:  "path to nested attribute",
+			field: "Provider/@ID",
+			record: map[string]interface{}{
+				"@ID": 12345,
+				"Provider": map[string]interface{}{
+					"@ID": 999,
+				},
+			},
+			extras:     map[string]interface{}{},
+			expected:   999,
+			expectedOk: true,
+		},
 		{
 			name:       "field does not exist",
 			field:      "imaginary",
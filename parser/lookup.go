@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/internal/maputil"
+	"havocai-assignment/models"
+)
+
+// lookupTransformation maps the value of a single field through the code table in extras["table"].
+// Codes that are not in the table use extras["default"], fail when extras["fail_on_missing"] is
+// true, and otherwise produce no value.
+func lookupTransformation(record map[string]interface{}, transformation models.Transformation) (interface{}, error) {
+	extras := transformation.Params.Extras
+	fields := transformation.Params.Fields
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("lookup requires exactly one field")
+	}
+
+	table, ok := extras["table"].(map[string]interface{})
+	if !ok {
+		if file, ok := extras["table_file"]; ok {
+			return nil, fmt.Errorf("lookup table file %v has not been loaded", file)
+		}
+		return nil, fmt.Errorf("lookup requires a table or table_file")
+	}
+
+	val, found := getFieldValue(fields[0], record, nil)
+	if found {
+		if mapped, ok := lookupCode(table, val); ok {
+			return mapped, nil
+		}
+	}
+
+	if defaultVal, ok := extras["default"]; ok {
+		return defaultVal, nil
+	}
+	if fail, _ := extras["fail_on_missing"].(bool); fail {
+		if !found {
+			return nil, fmt.Errorf("field %v not found in XML", fields[0])
+		}
+		return nil, fmt.Errorf("code %v is not in the lookup table", val)
+	}
+	return nil, nil
+}
+
+// lookupCode returns the entry of a table for a code. Tables are always keyed by text, while codes
+// have been parsed into numbers and booleans where possible, so that "F" arrives as false and
+// "1.50" as 1.5. A code that is not in the table as text is matched against the keys parsed the
+// same way, in sorted order.
+func lookupCode(table map[string]interface{}, code interface{}) (interface{}, bool) {
+	if mapped, ok := table[fmt.Sprintf("%v", code)]; ok {
+		return mapped, true
+	}
+	switch code.(type) {
+	case bool, int, float64:
+	default:
+		return nil, false
+	}
+
+	for _, key := range maputil.SortedKeys(table) {
+		if parseValue(key) == code {
+			return table[key], true
+		}
+	}
+	return nil, false
+}
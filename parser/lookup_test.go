@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupTransformation(t *testing.T) {
+	table := map[string]interface{}{
+		"M": "Male",
+		"F": "Female",
+		"1": "2106-3",
+	}
+
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		extras      map[string]interface{}
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:        "code in table",
+			record:      map[string]interface{}{"Code": "F"},
+			extras:      map[string]interface{}{"table": table},
+			expected:    "Female",
+			expectedErr: false,
+		},
+		{
+			name:        "code parsed as false",
+			record:      map[string]interface{}{"Code": false},
+			extras:      map[string]interface{}{"table": table},
+			expected:    "Female",
+			expectedErr: false,
+		},
+		{
+			name:        "code parsed as true",
+			record:      map[string]interface{}{"Code": true},
+			extras:      map[string]interface{}{"table": map[string]interface{}{"T": "Transgender", "F": "Female"}},
+			expected:    "Transgender",
+			expectedErr: false,
+		},
+		{
+			name:        "code parsed as a decimal",
+			record:      map[string]interface{}{"Code": 1.5},
+			extras:      map[string]interface{}{"table": map[string]interface{}{"1.50": "Copay"}},
+			expected:    "Copay",
+			expectedErr: false,
+		},
+		{
+			name:        "bool without a matching code uses default",
+			record:      map[string]interface{}{"Code": true},
+			extras:      map[string]interface{}{"table": table, "default": "Unknown"},
+			expected:    "Unknown",
+			expectedErr: false,
+		},
+		{
+			name:        "numeric code in table",
+			record:      map[string]interface{}{"Code": 1},
+			extras:      map[string]interface{}{"table": table},
+			expected:    "2106-3",
+			expectedErr: false,
+		},
+		{
+			name:        "unknown code uses default",
+			record:      map[string]interface{}{"Code": "X"},
+			extras:      map[string]interface{}{"table": table, "default": "Unknown"},
+			expected:    "Unknown",
+			expectedErr: false,
+		},
+		{
+			name:        "missing field uses default",
+			record:      map[string]interface{}{},
+			extras:      map[string]interface{}{"table": table, "default": "Unknown"},
+			expected:    "Unknown",
+			expectedErr: false,
+		},
+		{
+			name:        "unknown code without default",
+			record:      map[string]interface{}{"Code": "X"},
+			extras:      map[string]interface{}{"table": table},
+			expected:    nil,
+			expectedErr: false,
+		},
+		{
+			name:        "unknown code fails",
+			record:      map[string]interface{}{"Code": "X"},
+			extras:      map[string]interface{}{"table": table, "fail_on_missing": true},
+			expectedErr: true,
+		},
+		{
+			name:        "table file not loaded",
+			record:      map[string]interface{}{"Code": "M"},
+			extras:      map[string]interface{}{"table_file": "gender.csv"},
+			expectedErr: true,
+		},
+		{
+			name:        "no table",
+			record:      map[string]interface{}{"Code": "M"},
+			extras:      map[string]interface{}{},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformation := models.Transformation{
+				Type: "lookup",
+				Params: models.Params{
+					Fields: []string{"Code"},
+					Extras: test.extras,
+				},
+			}
+			actual, err := lookupTransformation(test.record, transformation)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"encoding/xml"
+)
+
+// xmlNamespace is the namespace that the reserved "xml" prefix (e.g. xml:lang) is bound to.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// namespaceScope tracks the namespace declarations of the open elements so that element and
+// attribute names can be written as "prefix:Name". Prefixes bound in the config take priority
+// over the ones used in the document, so a config does not depend on the prefixes a sender uses.
+type namespaceScope struct {
+	// prefixes maps a namespace URI to the prefix it was given in the config
+	prefixes map[string]string
+	// declared holds the prefix to URI declarations made on each open element
+	declared []map[string]string
+}
+
+func newNamespaceScope(bindings map[string]string) *namespaceScope {
+	prefixes := map[string]string{xmlNamespace: "xml"}
+	for prefix, uri := range bindings {
+		prefixes[uri] = prefix
+	}
+	return &namespaceScope{prefixes: prefixes}
+}
+
+// push records the namespace declarations made by a start element.
+func (ns *namespaceScope) push(attrs []xml.Attr) {
+	declared := make(map[string]string)
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" {
+			declared[attr.Name.Local] = attr.Value
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			declared[""] = attr.Value
+		}
+	}
+	ns.declared = append(ns.declared, declared)
+}
+
+// pop removes the declarations of the element that just ended.
+func (ns *namespaceScope) pop() {
+	ns.declared = ns.declared[:len(ns.declared)-1]
+}
+
+// elementName returns the name an element is stored under. Elements in the default namespace
+// keep their local name unless the config binds a prefix to it.
+func (ns *namespaceScope) elementName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if prefix, ok := ns.prefixes[name.Space]; ok {
+		return prefix + ":" + name.Local
+	}
+	if uri, ok := ns.lookup(""); ok && uri == name.Space {
+		return name.Local
+	}
+	return ns.documentPrefix(name.Space) + ":" + name.Local
+}
+
+// attrName returns the name an attribute is stored under, without the attribute prefix.
+// Unprefixed attributes are never in a namespace.
+func (ns *namespaceScope) attrName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	if name.Space == "xmlns" {
+		return "xmlns:" + name.Local
+	}
+	if prefix, ok := ns.prefixes[name.Space]; ok {
+		return prefix + ":" + name.Local
+	}
+	return ns.documentPrefix(name.Space) + ":" + name.Local
+}
+
+// lookup returns the URI a prefix is bound to in the document at the current element.
+func (ns *namespaceScope) lookup(prefix string) (string, bool) {
+	for i := len(ns.declared) - 1; i >= 0; i-- {
+		if uri, ok := ns.declared[i][prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", false
+}
+
+// documentPrefix returns the prefix the document declared for a namespace URI. A prefix that
+// was never declared is left as is by the decoder, so it is returned unchanged.
+func (ns *namespaceScope) documentPrefix(uri string) string {
+	for i := len(ns.declared) - 1; i >= 0; i-- {
+		for prefix, declaredURI := range ns.declared[i] {
+			if prefix != "" && declaredURI == uri {
+				return prefix
+			}
+		}
+	}
+	return uri
+}
+
+// isNamespaceDeclaration reports whether an attribute is an xmlns declaration.
+func isNamespaceDeclaration(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+}
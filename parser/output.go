@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// appendSuffix marks an output field that collects values into an array, e.g. "contact.phones[]".
+const appendSuffix = "[]"
+
+// setOutputValue stores a value in the output record. Dots in the field name build nested
+// objects, so "address.street" is stored as {"address": {"street": value}}. A name ending in
+// "[]" appends to an array instead of replacing the value; lists are appended item by item.
+func setOutputValue(output map[string]interface{}, field string, value interface{}) error {
+	names := strings.Split(field, ".")
+	current := output
+	for i, name := range names[:len(names)-1] {
+		if name == "" {
+			return fmt.Errorf("invalid output field %q: empty name", field)
+		}
+		if strings.HasSuffix(name, appendSuffix) {
+			return fmt.Errorf("invalid output field %q: only the last name can be an array", field)
+		}
+
+		existing, ok := current[name]
+		if !ok {
+			nested := make(map[string]interface{})
+			current[name] = nested
+			current = nested
+			continue
+		}
+
+		nested, ok := existing.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid output field %q: %v is already set to a value", field, strings.Join(names[:i+1], "."))
+		}
+		current = nested
+	}
+
+	name := names[len(names)-1]
+	if !strings.HasSuffix(name, appendSuffix) {
+		if name == "" {
+			return fmt.Errorf("invalid output field %q: empty name", field)
+		}
+		current[name] = value
+		return nil
+	}
+
+	name = strings.TrimSuffix(name, appendSuffix)
+	if name == "" {
+		return fmt.Errorf("invalid output field %q: empty name", field)
+	}
+
+	var list []interface{}
+	if existing, ok := current[name]; ok {
+		list, ok = existing.([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid output field %q: %v is already set to a value that is not an array", field, name)
+		}
+	}
+
+	if values, ok := value.([]interface{}); ok {
+		list = append(list, values...)
+	} else {
+		list = append(list, value)
+	}
+	current[name] = list
+	return nil
+}
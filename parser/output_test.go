@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOutputValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      map[string]interface{}
+		field       string
+		value       interface{}
+		expected    map[string]interface{}
+		expectedErr bool
+	}{
+		{
+			name:     "top level field",
+			output:   map[string]interface{}{},
+			field:    "id",
+			value:    12345,
+			expected: map[string]interface{}{"id": 12345},
+		},
+		{
+			name:   "nested field",
+			output: map[string]interface{}{},
+			field:  "address.street",
+			value:  "123 Havoc Way",
+			expected: map[string]interface{}{
+				"address": map[string]interface{}{"street": "123 Havoc Way"},
+			},
+		},
+		{
+			name: "nested field added to existing object",
+			output: map[string]interface{}{
+				"address": map[string]interface{}{"street": "123 Havoc Way"},
+			},
+			field: "address.zip",
+			value: "02860",
+			expected: map[string]interface{}{
+				"address": map[string]interface{}{"street": "123 Havoc Way", "zip": "02860"},
+			},
+		},
+		{
+			name:   "new array",
+			output: map[string]interface{}{},
+			field:  "contact.phones[]",
+			value:  "123-456-7890",
+			expected: map[string]interface{}{
+				"contact": map[string]interface{}{"phones": []interface{}{"123-456-7890"}},
+			},
+		},
+		{
+			name: "append to existing array",
+			output: map[string]interface{}{
+				"contact": map[string]interface{}{"phones": []interface{}{"123-456-7890"}},
+			},
+			field: "contact.phones[]",
+			value: []interface{}{"555-555-5555", "888-555-1234"},
+			expected: map[string]interface{}{
+				"contact": map[string]interface{}{"phones": []interface{}{"123-456-7890", "555-555-5555", "888-555-1234"}},
+			},
+		},
+		{
+			name:        "nested under existing value",
+			output:      map[string]interface{}{"address": "123 Havoc Way"},
+			field:       "address.street",
+			value:       "123 Havoc Way",
+			expectedErr: true,
+		},
+		{
+			name:        "append to existing value",
+			output:      map[string]interface{}{"phones": "123-456-7890"},
+			field:       "phones[]",
+			value:       "555-555-5555",
+			expectedErr: true,
+		},
+		{
+			name:        "array before last name",
+			output:      map[string]interface{}{},
+			field:       "contacts[].phone",
+			value:       "555-555-5555",
+			expectedErr: true,
+		},
+		{
+			name:        "empty name",
+			output:      map[string]interface{}{},
+			field:       "address..street",
+			value:       "123 Havoc Way",
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := setOutputValue(test.output, test.field, test.value)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, test.output)
+			}
+		})
+	}
+}
@@ -2,64 +2,33 @@ package parser
 
 import (
 	"bytes"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
+	"havocai-assignment/internal/maputil"
 	"havocai-assignment/models"
+	"io"
 	"math"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func ParseXML(input []byte) ([]map[string]interface{}, error) {
-	decoder := xml.NewDecoder(bytes.NewReader(input))
+// ParseXML parses the input into a list of records. Records are the elements matched by the
+// config's record_path, or every child of the document root when no record_path is set.
+// See RecordDecoder for how each record is built.
+func ParseXML(input []byte, cfg *models.Config) ([]map[string]interface{}, error) {
+	decoder := NewRecordDecoder(bytes.NewReader(input), cfg)
 	var results []map[string]interface{}
 
-	// to track current data within loop
-	currentData := make(map[string]interface{})
-	currentElementName := ""
-	//track nesting to determine when to exit a grouping
-	level := 0
-
 	for {
-		token, err := decoder.Token()
+		record, err := decoder.Next()
 		if err != nil {
-			if err.Error() == "EOF" {
+			if err == io.EOF {
 				// end of XML returns EOF, break from loop
 				break
 			}
 			return nil, err
 		}
-
-		switch t := token.(type) {
-		case xml.StartElement:
-			// when we encounter a start element, want to increment level (noting we are within an element) and track the current element name
-			level++
-			currentElementName = t.Name.Local
-
-			// need to handle scenario where a start element has attributes
-			for _, attr := range t.Attr {
-				currentData[attr.Name.Local] = parseValue(attr.Value)
-			}
-		case xml.EndElement:
-			// when we encounter an end element, want to decrease level (exiting an element) and then append data to results and reset currentData and currentElementName
-			level--
-
-			// if level == 1, we are at the end of a grouping
-			if level == 1 {
-				results = append(results, currentData)
-				currentData = make(map[string]interface{})
-			}
-
-			currentElementName = ""
-		case xml.CharData:
-			// when we encounter CharData, store the character data at the current element
-			content := strings.TrimSpace(string(t))
-			if currentElementName != "" && content != "" {
-				currentData[currentElementName] = parseValue(content)
-			}
-		}
+		results = append(results, record)
 	}
 	return results, nil
 }
@@ -84,66 +53,235 @@ func parseValue(val string) interface{} {
 	return val
 }
 
+// ConvertToJSON transforms already parsed records and returns them as a single JSON document.
+// Use Stream or converter.Converter to convert large inputs without holding every record in memory.
 func ConvertToJSON(input []map[string]interface{}, cfg *models.Config) ([]byte, error) {
-	transformedInput, err := applyTransformations(input, cfg)
+	transformer, err := NewRecordTransformer(cfg, Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer, err := NewJSONWriter(&buf, cfg.RootName)
 	if err != nil {
 		return nil, err
 	}
 
-	wrappedOutput := map[string]interface{}{
-		cfg.RootName: transformedInput,
+	for i, record := range input {
+		transformed, err := transformer.Transform(record)
+		if err != nil {
+			return nil, &RecordError{Index: i, Err: err}
+		}
+		if err := writer.Write(transformed); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Options controls how records are transformed.
+type Options struct {
+	// Now returns the time used wherever a transformation refers to CurrentTime.
+	// When nil, the config's reference_time is used, or time.Now when it has none.
+	Now func() time.Time
+
+	// dates is set by NewRecordTransformer so that date parsers are built once per config
+	dates *dateParsers
+}
+
+// dateParser returns the dateParser for the date extras of a transformation.
+func (opts Options) dateParser(extras map[string]interface{}) (dateParser, error) {
+	if opts.dates == nil {
+		return newDateParser(extras)
+	}
+	return opts.dates.get(extras)
+}
+
+// ParseTime parses a reference time written as an RFC3339 timestamp (e.g. 2025-01-29T00:00:00Z)
+// or as a date (e.g. 2025-01-29), which is read as midnight UTC.
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: must be an RFC3339 timestamp or a YYYY-MM-DD date", value)
+	}
+	return t, nil
+}
+
+// CurrentTime returns the time that transformations should use as the current time.
+func (opts Options) CurrentTime() time.Time {
+	if opts.Now == nil {
+		return time.Now()
+	}
+	return opts.Now()
+}
+
+// applyFieldOptions fills in an output field that has no value from its coalesce fields or its
+// default, and fails with ErrRequiredField when a required field is still without a value.
+// It reports whether the returned value should be used.
+func applyFieldOptions(val interface{}, hasValue bool, fieldOpts models.FieldOptions, record map[string]interface{}) (interface{}, bool, error) {
+	if hasValue {
+		return val, true, nil
+	}
+
+	for _, field := range fieldOpts.Coalesce {
+		if coalesced, found := getFieldValue(field, record, nil); found && !isEmpty(coalesced) {
+			return coalesced, true, nil
+		}
+	}
+
+	if fieldOpts.Default != nil {
+		return fieldOpts.Default, true, nil
+	}
+	if fieldOpts.Required {
+		return nil, false, ErrRequiredField
+	}
+	return val, false, nil
+}
+
+// RecordTransformer applies a config's mappings and transformations to parsed records.
+// Transformations can refer to output fields that were mapped or produced by other
+// transformations as "$name", and are run after the transformations they refer to. The order
+// is worked out once, when the RecordTransformer is created, and a RecordTransformer can be used
+// for any number of records.
+type RecordTransformer struct {
+	cfg   *models.Config
+	opts  Options
+	order []string
+	// transformations are the config's transformations with their branch transformations decoded
+	transformations map[string]models.Transformation
+	// castDates reads the values of the "date" output_type
+	castDates dateParser
+}
+
+// NewRecordTransformer creates a RecordTransformer for the config. When opts has no Now and the
+// config sets a reference_time, CurrentTime is pinned to the reference_time. Transformations that
+// refer to each other in a cycle are reported here, before any record is transformed, and the
+// transformations run by if branches are decoded here rather than for every record.
+func NewRecordTransformer(cfg *models.Config, opts Options) (*RecordTransformer, error) {
+	if opts.Now == nil && cfg.ReferenceTime != "" {
+		referenceTime, err := ParseTime(cfg.ReferenceTime)
+		if err != nil {
+			return nil, fmt.Errorf("reference_time: %w", err)
+		}
+		opts.Now = func() time.Time { return referenceTime }
 	}
+	castDates, err := newCastDateParser(cfg.Dates)
+	if err != nil {
+		return nil, fmt.Errorf("dates: %w", err)
+	}
+	opts.dates = newDateParsers(cfg.Dates)
 
-	jsonOutput, err := json.MarshalIndent(wrappedOutput, "", "  ")
+	order, err := transformationOrder(cfg.Transformations)
 	if err != nil {
 		return nil, err
 	}
+	transformations := make(map[string]models.Transformation, len(cfg.Transformations))
+	for jsonField, transformation := range cfg.Transformations {
+		if transformations[jsonField], err = decodeBranches(transformation); err != nil {
+			return nil, fmt.Errorf("transformation %v: %w", jsonField, err)
+		}
+	}
+	return &RecordTransformer{cfg: cfg, opts: opts, order: order, transformations: transformations, castDates: castDates}, nil
+}
 
-	return jsonOutput, nil
+// TransformRecord applies the config's mappings and transformations to a single parsed record.
+// Use a RecordTransformer to transform more than one record with the same config.
+func TransformRecord(record map[string]interface{}, cfg *models.Config, opts Options) (map[string]interface{}, error) {
+	t, err := NewRecordTransformer(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	return t.Transform(record)
 }
 
-func applyTransformations(input []map[string]interface{}, cfg *models.Config) ([]map[string]interface{}, error) {
-	var output []map[string]interface{}
+// Transform applies the mappings and transformations to a single parsed record.
+func (t *RecordTransformer) Transform(record map[string]interface{}) (map[string]interface{}, error) {
+	cfg, opts, order := t.cfg, t.opts, t.order
 
-	for _, record := range input {
-		transformed := make(map[string]interface{})
-		// apply mappings based on 1:1 mapping definition
-		for xmlField, jsonField := range cfg.Mappings {
-			if val, ok := record[xmlField]; ok {
-				transformed[jsonField] = val
-			}
+	// scope holds the input record along with the output fields produced so far
+	scope := make(map[string]interface{}, len(record)+len(cfg.Mappings)+len(order))
+	for key, val := range record {
+		scope[key] = val
+	}
+
+	transformed := make(map[string]interface{})
+	// apply mappings based on 1:1 mapping definition, in a fixed order so that
+	// fields appended to the same output array always end up in the same order
+	for _, xmlField := range maputil.SortedKeys(cfg.Mappings) {
+		mapping := cfg.Mappings[xmlField]
+		val, present := getFieldValue(xmlField, record, nil)
+		val, found, err := applyFieldOptions(val, present && !isEmpty(val), mapping.FieldOptions, scope)
+		if err != nil {
+			return nil, &FieldError{Field: mapping.Field, Err: err}
+		}
+		// a mapping without a value is left out, unless its element is there but empty and
+		// null-if-empty asks for it to be written as null
+		if !found && !(present && mapping.OutputType == "null-if-empty") {
+			continue
+		}
+		if val, err = castValue(val, mapping.OutputType, t.castDates); err != nil {
+			return nil, &FieldError{Field: mapping.Field, Err: err}
 		}
 
-		for jsonField, transformation := range cfg.Transformations {
-			switch transformation.Type {
-			case "concat":
-				val, err := concatTransformation(record, transformation)
-				if err != nil {
-					return nil, err
-				}
-				transformed[jsonField] = val
-			case "calculate":
-				val, err := calculateTransformation(record, transformation)
-				if err != nil {
-					return nil, err
-				}
-				transformed[jsonField] = val
-			}
+		if err := setOutputValue(transformed, mapping.Field, val); err != nil {
+			return nil, &FieldError{Field: mapping.Field, Err: err}
+		}
+		scope[outputRef(mapping.Field)] = val
+	}
 
+	for _, jsonField := range order {
+		transformation := t.transformations[jsonField]
+		val, err := runTransformation(scope, transformation, opts)
+		if err != nil {
+			return nil, &FieldError{Field: jsonField, Err: err}
 		}
 
-		output = append(output, transformed)
+		// unlike mappings, transformations without a value are still written to the output
+		val, _, err = applyFieldOptions(val, !isEmpty(val), transformation.FieldOptions, scope)
+		if err != nil {
+			return nil, &FieldError{Field: jsonField, Err: err}
+		}
+		if val, err = castValue(val, transformation.OutputType, t.castDates); err != nil {
+			return nil, &FieldError{Field: jsonField, Err: err}
+		}
+
+		if err := setOutputValue(transformed, jsonField, val); err != nil {
+			return nil, &FieldError{Field: jsonField, Err: err}
+		}
+		scope[outputRef(jsonField)] = val
 	}
-	return output, nil
+	return transformed, nil
 }
 
+// concatTransformation joins the values of fields with extras["separator"]. Fields that are not in
+// the record are left out, unless extras["fail_on_missing"] is true, in which case the first one
+// fails the transformation.
 func concatTransformation(record map[string]interface{}, transformation models.Transformation) (string, error) {
 	fields := transformation.Params.Fields
+	failOnMissing, _ := transformation.Params.Extras["fail_on_missing"].(bool)
 
 	fieldValues := []string{}
 	for _, field := range fields {
-		value, ok := record[field]
+		value, ok := getFieldValue(field, record, nil)
 		if !ok {
+			if failOnMissing {
+				return "", fmt.Errorf("field %v not found", field)
+			}
+			continue
+		}
+
+		// repeated elements are joined using the same separator as the other fields
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				fieldValues = append(fieldValues, fmt.Sprintf("%v", item))
+			}
 			continue
 		}
 
@@ -159,7 +297,7 @@ func concatTransformation(record map[string]interface{}, transformation models.T
 	return strings.Join(fieldValues, separator), nil
 }
 
-func calculateTransformation(record map[string]interface{}, transformation models.Transformation) (interface{}, error) {
+func calculateTransformation(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
 	extras := transformation.Params.Extras
 	operation, ok := extras["operation"].(string)
 	if !ok {
@@ -168,13 +306,13 @@ func calculateTransformation(record map[string]interface{}, transformation model
 
 	fields := transformation.Params.Fields
 
-	format := time.RFC3339
-	if formatIface, ok := extras["format"]; ok {
-		format, _ = formatIface.(string)
+	dates, err := opts.dateParser(extras)
+	if err != nil {
+		return nil, err
 	}
 
 	if operation == "time_difference" {
-		return calculateTimeDifference(fields, record, extras, format)
+		return calculateTimeDifference(fields, record, extras, dates, opts.CurrentTime())
 	}
 
 	values := []float64{}
@@ -184,7 +322,7 @@ func calculateTransformation(record map[string]interface{}, transformation model
 			return nil, fmt.Errorf("field %v not found in XML or extras", field)
 		}
 
-		floatVal, err := toFloat64(val, format)
+		floatVal, err := toFloat64(val, dates)
 		if err != nil {
 			return nil, err
 		}
@@ -207,28 +345,26 @@ func calculateTransformation(record map[string]interface{}, transformation model
 	}
 }
 
-func parseDate(field string, record map[string]interface{}, extras map[string]interface{}, format string) (time.Time, error) {
+func parseDate(field string, record map[string]interface{}, extras map[string]interface{}, dates dateParser) (time.Time, error) {
 	val, found := getFieldValue(field, record, extras)
 	if !found {
 		return time.Time{}, fmt.Errorf("field %v not found in xml or in extras", field)
 	}
 
-	dateStr, ok := val.(string)
-	if !ok {
+	if _, ok := val.(string); !ok {
 		return time.Time{}, fmt.Errorf("field %v is not a string", field)
 	}
 
-	return time.Parse(format, dateStr)
+	return dates.parse(val)
 }
 
-func calculateTimeDifference(fields []string, record map[string]interface{}, extras map[string]interface{}, format string) (interface{}, error) {
+func calculateTimeDifference(fields []string, record map[string]interface{}, extras map[string]interface{}, dates dateParser, now time.Time) (interface{}, error) {
 	if len(fields) != 2 {
 		return nil, fmt.Errorf("time_difference requires two values")
 	}
 	startField := fields[0]
-	startDate, err := parseDate(startField, record, extras, format)
+	startDate, err := parseDate(startField, record, extras, dates)
 	if err != nil {
-		fmt.Println("err here")
 		return nil, err
 	}
 
@@ -236,9 +372,9 @@ func calculateTimeDifference(fields []string, record map[string]interface{}, ext
 	var endDate time.Time
 
 	if endField == "CurrentTime" {
-		endDate = time.Now()
+		endDate = now
 	} else {
-		endDate, err = parseDate(endField, record, extras, format)
+		endDate, err = parseDate(endField, record, extras, dates)
 		if err != nil {
 			return nil, err
 		}
@@ -249,30 +385,31 @@ func calculateTimeDifference(fields []string, record map[string]interface{}, ext
 		unit, _ = u.(string)
 	}
 
-	return calculateDuration(startDate, endDate, unit, extras)
+	// calendar units are counted in the output time zone, when there is one
+	return calculateDuration(dates.output(startDate), dates.output(endDate), unit, extras)
 }
 
 func calculateDuration(startDate time.Time, endDate time.Time, unit string, extras map[string]interface{}) (interface{}, error) {
 	var result float64
 	duration := endDate.Sub(startDate)
 
+	// years, months and days are counted on the calendar, the way ages are
+	years, months, days := calendarDiff(startDate, endDate)
+
 	switch unit {
 	case "years":
-		result = float64(endDate.Year() - startDate.Year())
-
-		//check if need to adjust if day in question has not passed yet this year
-		if adjust, ok := extras["adjust_if_day_not_passed"].(bool); ok && adjust {
-			if endDate.YearDay() < startDate.YearDay() {
-				result--
-			}
-		}
-		return result, nil
+		// adjust_if_day_not_passed is no longer needed, as a year only counts once its day is reached
+		return float64(years), nil
 	case "months":
-		result = duration.Hours() / (30.44 * 24)
+		return float64(years*12 + months), nil
+	case "years_months":
+		return map[string]interface{}{"years": years, "months": months}, nil
+	case "years_months_days":
+		return map[string]interface{}{"years": years, "months": months, "days": days}, nil
 	case "weeks":
 		result = duration.Hours() / (7 * 24)
 	case "days":
-		result = duration.Hours() / 24
+		result = float64(calendarDays(startDate, endDate))
 	case "hours":
 		result = duration.Hours()
 	case "minutes":
@@ -289,7 +426,10 @@ func calculateDuration(startDate time.Time, endDate time.Time, unit string, extr
 		return nil, fmt.Errorf("unsupported time unit: %v", unit)
 	}
 
-	if precision, ok := extras["decimal_precision"].(int); ok {
+	// decimal_precision is decoded from a JSON config as a float64, so it is read with intExtra
+	if precision, ok, err := intExtra(extras, "decimal_precision"); err != nil {
+		return nil, err
+	} else if ok {
 		multiplier := math.Pow(10, float64(precision))
 		return math.Round(result*multiplier) / multiplier, nil
 	}
@@ -1,10 +1,14 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
 	"havocai-assignment/models"
+	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -86,6 +90,25 @@ FooBar, WI
 12345`,
 			expectedErr: false,
 		},
+		{
+			name: "repeated elements are joined with separator",
+			record: map[string]interface{}{
+				"Allergy": []interface{}{"Peanuts", "Penicillin"},
+			},
+			transformation: models.Transformation{
+				Type: "concat",
+				Params: models.Params{
+					Fields: []string{
+						"Allergy",
+					},
+					Extras: map[string]interface{}{
+						"separator": ", ",
+					},
+				},
+			},
+			expected:    "Peanuts, Penicillin",
+			expectedErr: false,
+		},
 		{
 			name: "field not in record",
 			record: map[string]interface{}{
@@ -106,6 +129,26 @@ FooBar, WI
 			expected:    "Charlotte",
 			expectedErr: false,
 		},
+		{
+			name: "field not in record with fail_on_missing",
+			record: map[string]interface{}{
+				"FirstName": "Charlotte",
+			},
+			transformation: models.Transformation{
+				Type: "concat",
+				Params: models.Params{
+					Fields: []string{
+						"FirstName",
+						"LastName",
+					},
+					Extras: map[string]interface{}{
+						"separator":       " ",
+						"fail_on_missing": true,
+					},
+				},
+			},
+			expectedErr: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -301,6 +344,30 @@ func TestCalculateTransformation(t *testing.T) {
 			expected:    60.0,
 			expectedErr: false,
 		},
+		{
+			name: "Time difference with decimal precision from JSON",
+			record: map[string]interface{}{
+				"Start": "2025-01-05",
+				"End":   "2025-01-06",
+			},
+			transformation: models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{
+						"Start",
+						"End",
+					},
+					Extras: map[string]interface{}{
+						"operation":         "time_difference",
+						"format":            "2006-01-02",
+						"unit":              "weeks",
+						"decimal_precision": float64(2),
+					},
+				},
+			},
+			expected:    0.14,
+			expectedErr: false,
+		},
 		{
 			name: "Time difference in weeks, rounded to int",
 			record: map[string]interface{}{
@@ -326,7 +393,7 @@ func TestCalculateTransformation(t *testing.T) {
 			expectedErr: false,
 		},
 		{
-			name: "Time difference in months, with decimal precision",
+			name: "Time difference in calendar months",
 			record: map[string]interface{}{
 				"Start": "2025-01-05",
 				"End":   "2025-03-05",
@@ -339,21 +406,135 @@ func TestCalculateTransformation(t *testing.T) {
 						"End",
 					},
 					Extras: map[string]interface{}{
-						"operation":         "time_difference",
-						"format":            "2006-01-02",
-						"unit":              "months",
-						"decimal_precision": 3,
+						"operation": "time_difference",
+						"format":    "2006-01-02",
+						"unit":      "months",
+					},
+				},
+			},
+			expected:    2.0,
+			expectedErr: false,
+		},
+		{
+			name: "Time difference in years, born on leap day",
+			record: map[string]interface{}{
+				"Start": "2000-02-29",
+				"End":   "2023-02-28",
+			},
+			transformation: models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{
+						"Start",
+						"End",
+					},
+					Extras: map[string]interface{}{
+						"operation": "time_difference",
+						"format":    "2006-01-02",
+						"unit":      "years",
+					},
+				},
+			},
+			expected:    22.0,
+			expectedErr: false,
+		},
+		{
+			name: "Time difference with mixed date layouts",
+			record: map[string]interface{}{
+				"Start": "01/05/2025",
+				"End":   "2025-03-05T10:00:00Z",
+			},
+			transformation: models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{
+						"Start",
+						"End",
+					},
+					Extras: map[string]interface{}{
+						"operation": "time_difference",
+						"format":    []interface{}{"2006-01-02", "01/02/2006", time.RFC3339},
+						"unit":      "months",
+					},
+				},
+			},
+			expected:    2.0,
+			expectedErr: false,
+		},
+		{
+			name: "Time difference counted in output timezone",
+			record: map[string]interface{}{
+				"Start": "2024-01-29T23:00:00Z",
+				"End":   "2025-01-30T02:00:00Z",
+			},
+			transformation: models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{
+						"Start",
+						"End",
+					},
+					Extras: map[string]interface{}{
+						"operation":       "time_difference",
+						"unit":            "years_months_days",
+						"output_timezone": "America/New_York",
+					},
+				},
+			},
+			expected:    map[string]interface{}{"years": 1, "months": 0, "days": 0},
+			expectedErr: false,
+		},
+		{
+			name: "Time difference in years and months",
+			record: map[string]interface{}{
+				"Start": "2021-11-20",
+				"End":   "2025-01-29",
+			},
+			transformation: models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{
+						"Start",
+						"End",
+					},
+					Extras: map[string]interface{}{
+						"operation": "time_difference",
+						"format":    "2006-01-02",
+						"unit":      "years_months",
+					},
+				},
+			},
+			expected:    map[string]interface{}{"years": 3, "months": 2},
+			expectedErr: false,
+		},
+		{
+			name: "Time difference in years, months and days",
+			record: map[string]interface{}{
+				"Start": "2021-11-20",
+				"End":   "2025-01-29",
+			},
+			transformation: models.Transformation{
+				Type: "calculate",
+				Params: models.Params{
+					Fields: []string{
+						"Start",
+						"End",
+					},
+					Extras: map[string]interface{}{
+						"operation": "time_difference",
+						"format":    "2006-01-02",
+						"unit":      "years_months_days",
 					},
 				},
 			},
-			expected:    1.938,
+			expected:    map[string]interface{}{"years": 3, "months": 2, "days": 9},
 			expectedErr: false,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			actual, err := calculateTransformation(test.record, test.transformation)
+			actual, err := calculateTransformation(test.record, test.transformation, Options{})
 			if test.expectedErr {
 				require.Error(t, err)
 			} else {
@@ -368,6 +549,7 @@ func TestParseXML(t *testing.T) {
 	tests := []struct {
 		name          string
 		inputFilePath string
+		config        *models.Config
 		expected      []map[string]interface{}
 		expectedErr   bool
 	}{
@@ -376,7 +558,7 @@ func TestParseXML(t *testing.T) {
 			inputFilePath: "../test/testdata/basicpatient/single_patient.xml",
 			expected: []map[string]interface{}{
 				{
-					"ID":          12345,
+					"@ID":         12345,
 					"DateOfBirth": "1993-07-06",
 					"FirstName":   "Charlotte",
 					"LastName":    "Taylor",
@@ -389,13 +571,13 @@ func TestParseXML(t *testing.T) {
 			inputFilePath: "../test/testdata/basicpatient/multiple_patients.xml",
 			expected: []map[string]interface{}{
 				{
-					"ID":          12345,
+					"@ID":         12345,
 					"DateOfBirth": "1993-07-06",
 					"FirstName":   "Charlotte",
 					"LastName":    "Taylor",
 				},
 				{
-					"ID":          53425,
+					"@ID":         53425,
 					"DateOfBirth": "1920-11-25",
 					"FirstName":   "Jane",
 					"LastName":    "Doe",
@@ -404,112 +586,434 @@ func TestParseXML(t *testing.T) {
 			expectedErr: false,
 		},
 		{
-			name:          "invalid xml",
-			inputFilePath: "../test/testdata/basicpatient/invalid_xml.xml",
-			expected:      nil,
-			expectedErr:   true,
+			name:          "nested elements",
+			inputFilePath: "../test/testdata/inputchanges/nested_fields.xml",
+			expected: []map[string]interface{}{
+				{
+					"@ID":       12345,
+					"FirstName": "John",
+					"LastName":  "Doe",
+					"Address": map[string]interface{}{
+						"Street":    "123 Havoc Way",
+						"CityState": "Providence, RI",
+						"ZipCode":   "02860",
+					},
+					"DateOfBirth": "1985-07-15",
+				},
+				{
+					"@ID":       67890,
+					"FirstName": "Jane",
+					"LastName":  "Smith",
+					"Address": map[string]interface{}{
+						"Street":    "888 Reginald Ave",
+						"Unit":      "Apartment 123",
+						"CityState": "Muskegon, KY",
+						"ZipCode":   12482,
+					},
+					"DateOfBirth": "1992-03-22",
+				},
+			},
+			expectedErr: false,
 		},
-	}
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			xmlData, err := os.ReadFile(test.inputFilePath)
-			require.NoError(t, err)
-
-			actual, err := ParseXML(xmlData)
-
-			if test.expectedErr {
-				require.Error(t, err)
-				require.Nil(t, actual)
-			} else {
-				require.NoError(t, err)
-				require.Equal(t, test.expected, actual)
-			}
-		})
-	}
-
-}
-
-func TestConvertToJSON(t *testing.T) {
-	tests := []struct {
-		name        string
-		input       []map[string]interface{}
-		config      *models.Config
-		expected    string
-		expectedErr bool
-	}{
 		{
-			name: "Basic mapping",
-			input: []map[string]interface{}{
-				{"old_field": "value1"},
-			},
-			config: &models.Config{
-				RootName: "data",
-				Mappings: map[string]string{
-					"old_field": "new_field",
+			name:          "element with attributes and text",
+			inputFilePath: "../test/testdata/basicpatient/attributes_and_text.xml",
+			expected: []map[string]interface{}{
+				{
+					"@ID": 12345,
+					"Phone": map[string]interface{}{
+						"@type": "home",
+						"#text": "555-555-5555",
+					},
 				},
 			},
-			expected: `{
-				"data": [{
-					"new_field": "value1"
-				}]
-			}`,
 			expectedErr: false,
 		},
 		{
-			name: "Concat transformation",
-			input: []map[string]interface{}{
-				{"first": "John", "last": "Doe"},
+			name:          "repeated elements",
+			inputFilePath: "../test/testdata/inputchanges/repeated_fields.xml",
+			expected: []map[string]interface{}{
+				{
+					"@ID":         12345,
+					"FirstName":   "John",
+					"LastName":    "Doe",
+					"Allergy":     []interface{}{"Peanuts", "Penicillin"},
+					"Phone":       "123-456-7890",
+					"DateOfBirth": "1985-07-15",
+				},
 			},
+			expectedErr: false,
+		},
+		{
+			name:          "single element forced to array",
+			inputFilePath: "../test/testdata/inputchanges/repeated_fields.xml",
 			config: &models.Config{
-				RootName: "users",
-				Transformations: map[string]models.Transformation{
-					"full_name": {
-						Type: "concat",
-						Params: models.Params{
-							Fields: []string{"first", "last"},
-							Extras: map[string]interface{}{"separator": " "},
-						},
-					},
+				ForceArray: []string{"Phone"},
+			},
+			expected: []map[string]interface{}{
+				{
+					"@ID":         12345,
+					"FirstName":   "John",
+					"LastName":    "Doe",
+					"Allergy":     []interface{}{"Peanuts", "Penicillin"},
+					"Phone":       []interface{}{"123-456-7890"},
+					"DateOfBirth": "1985-07-15",
 				},
 			},
-			expected: `{
-				"users": [{
-					"full_name": "John Doe"
-				}]
-			}`,
 			expectedErr: false,
 		},
 		{
-			name: "Transformation with missing field should skip missing field",
-			input: []map[string]interface{}{
-				{"first": "John"},
-			},
+			name:          "records selected by record path",
+			inputFilePath: "../test/testdata/recordpath/envelope.xml",
 			config: &models.Config{
-				RootName: "users",
-				Transformations: map[string]models.Transformation{
-					"full_name": {
-						Type: "concat",
-						Params: models.Params{
-							Fields: []string{"first", "last"},
-							Extras: map[string]interface{}{"separator": " "},
-						},
-					},
+				RecordPath: "Export/Patients/Patient",
+			},
+			expected: []map[string]interface{}{
+				{
+					"@ID":         12345,
+					"FirstName":   "John",
+					"LastName":    "Doe",
+					"DateOfBirth": "1985-07-15",
+				},
+				{
+					"@ID":         67890,
+					"FirstName":   "Jane",
+					"LastName":    "Smith",
+					"DateOfBirth": "1992-03-22",
 				},
 			},
-			expected: `{
-				"users": [{
-					"full_name": "John"
-				}]
-			}`,
 			expectedErr: false,
 		},
 		{
-			name: "Calculation transformation",
-			input: []map[string]interface{}{
-				{"a": 5, "b": 3},
-			},
+			name:          "records selected by record path with wildcard",
+			inputFilePath: "../test/testdata/recordpath/envelope.xml",
 			config: &models.Config{
-				RootName: "calculations",
+				RecordPath: "/*/Header",
+			},
+			expected: []map[string]interface{}{
+				{
+					"Vendor":      "Acme Health",
+					"GeneratedAt": "2025-01-29T00:00:00Z",
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "record path that matches nothing",
+			inputFilePath: "../test/testdata/recordpath/envelope.xml",
+			config: &models.Config{
+				RecordPath: "Export/Patient",
+			},
+			expected:    nil,
+			expectedErr: false,
+		},
+		{
+			name:          "namespaces use document prefixes",
+			inputFilePath: "../test/testdata/namespaces/input.xml",
+			expected: []map[string]interface{}{
+				{
+					"@id":      12345,
+					"@ext:id":  "A-12345",
+					"name":     "John Doe",
+					"ext:name": "Johnny",
+					"value": map[string]interface{}{
+						"@xsi:type": "PQ",
+						"#text":     5,
+					},
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "namespaces use config prefixes",
+			inputFilePath: "../test/testdata/namespaces/input.xml",
+			config: &models.Config{
+				RecordPath: "hl7:ClinicalDocument/hl7:patient",
+				Namespaces: map[string]string{
+					"hl7": "urn:hl7-org:v3",
+					"x":   "urn:example:ext",
+				},
+			},
+			expected: []map[string]interface{}{
+				{
+					"@id":      12345,
+					"@x:id":    "A-12345",
+					"hl7:name": "John Doe",
+					"x:name":   "Johnny",
+					"hl7:value": map[string]interface{}{
+						"@xsi:type": "PQ",
+						"#text":     5,
+					},
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "namespace declarations kept",
+			inputFilePath: "../test/testdata/namespaces/input.xml",
+			config: &models.Config{
+				RecordPath:                "*/*",
+				KeepNamespaceDeclarations: true,
+			},
+			expected: []map[string]interface{}{
+				{
+					"@id":          12345,
+					"@ext:id":      "A-12345",
+					"@xmlns:local": "urn:example:local",
+					"name":         "John Doe",
+					"ext:name":     "Johnny",
+					"value": map[string]interface{}{
+						"@xsi:type": "PQ",
+						"#text":     5,
+					},
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "auto type inference",
+			inputFilePath: "../test/testdata/types/input.xml",
+			expected: []map[string]interface{}{
+				{
+					"@MRN":   "000123",
+					"Zip":    "02860",
+					"Phone":  5555551234,
+					"Active": true,
+					"Weight": 72.5,
+					"Visits": 3,
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "strings-only type inference",
+			inputFilePath: "../test/testdata/types/input.xml",
+			config: &models.Config{
+				Types: models.Types{Inference: "strings-only"},
+			},
+			expected: []map[string]interface{}{
+				{
+					"@MRN":   "000123",
+					"Zip":    "02860",
+					"Phone":  "5555551234",
+					"Active": "T",
+					"Weight": "72.50",
+					"Visits": "3",
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "strict type inference",
+			inputFilePath: "../test/testdata/types/input.xml",
+			config: &models.Config{
+				Types: models.Types{Inference: "strict"},
+			},
+			expected: []map[string]interface{}{
+				{
+					"@MRN":   "000123",
+					"Zip":    "02860",
+					"Phone":  5555551234,
+					"Active": "T",
+					"Weight": 72.5,
+					"Visits": 3,
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "type hints",
+			inputFilePath: "../test/testdata/types/input.xml",
+			config: &models.Config{
+				Types: models.Types{
+					Inference: "strings-only",
+					Hints: map[string]string{
+						"@MRN":   "int",
+						"Active": "bool",
+						"Visits": "int",
+					},
+				},
+			},
+			expected: []map[string]interface{}{
+				{
+					"@MRN":   123,
+					"Zip":    "02860",
+					"Phone":  "5555551234",
+					"Active": true,
+					"Weight": "72.50",
+					"Visits": 3,
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name:          "value does not match type hint",
+			inputFilePath: "../test/testdata/types/input.xml",
+			config: &models.Config{
+				Types: models.Types{
+					Hints: map[string]string{"Weight": "int"},
+				},
+			},
+			expected:    nil,
+			expectedErr: true,
+		},
+		{
+			name:          "unsupported type inference",
+			inputFilePath: "../test/testdata/types/input.xml",
+			config: &models.Config{
+				Types: models.Types{Inference: "guess"},
+			},
+			expected:    nil,
+			expectedErr: true,
+		},
+		{
+			name:          "invalid xml",
+			inputFilePath: "../test/testdata/basicpatient/invalid_xml.xml",
+			expected:      nil,
+			expectedErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			xmlData, err := os.ReadFile(test.inputFilePath)
+			require.NoError(t, err)
+
+			actual, err := ParseXML(xmlData, test.config)
+
+			if test.expectedErr {
+				require.Error(t, err)
+				require.Nil(t, actual)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+
+}
+
+func TestConvertToJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []map[string]interface{}
+		config      *models.Config
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "Basic mapping",
+			input: []map[string]interface{}{
+				{"old_field": "value1"},
+			},
+			config: &models.Config{
+				RootName: "data",
+				Mappings: map[string]models.Mapping{
+					"old_field": {Field: "new_field"},
+				},
+			},
+			expected: `{
+				"data": [{
+					"new_field": "value1"
+				}]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name: "Concat transformation",
+			input: []map[string]interface{}{
+				{"first": "John", "last": "Doe"},
+			},
+			config: &models.Config{
+				RootName: "users",
+				Transformations: map[string]models.Transformation{
+					"full_name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"first", "last"},
+							Extras: map[string]interface{}{"separator": " "},
+						},
+					},
+				},
+			},
+			expected: `{
+				"users": [{
+					"full_name": "John Doe"
+				}]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name: "Transformation with missing field should skip missing field",
+			input: []map[string]interface{}{
+				{"first": "John"},
+			},
+			config: &models.Config{
+				RootName: "users",
+				Transformations: map[string]models.Transformation{
+					"full_name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"first", "last"},
+							Extras: map[string]interface{}{"separator": " "},
+						},
+					},
+				},
+			},
+			expected: `{
+				"users": [{
+					"full_name": "John"
+				}]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name: "Nested output fields",
+			input: []map[string]interface{}{
+				{
+					"first": "John",
+					"last":  "Doe",
+					"Address": map[string]interface{}{
+						"Street": "123 Havoc Way",
+					},
+					"Phone":  []interface{}{"123-456-7890", "555-555-5555"},
+					"Mobile": "888-555-1234",
+				},
+			},
+			config: &models.Config{
+				RootName: "users",
+				Mappings: map[string]models.Mapping{
+					"Address/Street": {Field: "address.street"},
+					"Mobile":         {Field: "contact.phones[]"},
+					"Phone":          {Field: "contact.phones[]"},
+				},
+				Transformations: map[string]models.Transformation{
+					"contact.name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"first", "last"},
+							Extras: map[string]interface{}{"separator": " "},
+						},
+					},
+				},
+			},
+			expected: `{
+				"users": [{
+					"address": {"street": "123 Havoc Way"},
+					"contact": {
+						"name": "John Doe",
+						"phones": ["888-555-1234", "123-456-7890", "555-555-5555"]
+					}
+				}]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name: "Calculation transformation",
+			input: []map[string]interface{}{
+				{"a": 5, "b": 3},
+			},
+			config: &models.Config{
+				RootName: "calculations",
 				Transformations: map[string]models.Transformation{
 					"sum": {
 						Type: "calculate",
@@ -527,6 +1031,44 @@ func TestConvertToJSON(t *testing.T) {
 			}`,
 			expectedErr: false,
 		},
+		{
+			name: "CurrentTime pinned by reference_time",
+			input: []map[string]interface{}{
+				{"DateOfBirth": "1985-07-15"},
+			},
+			config: &models.Config{
+				RootName:      "patients",
+				ReferenceTime: "2025-01-29",
+				Transformations: map[string]models.Transformation{
+					"age": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"DateOfBirth", "CurrentTime"},
+							Extras: map[string]interface{}{
+								"operation": "time_difference",
+								"format":    "2006-01-02",
+								"unit":      "years",
+							},
+						},
+					},
+				},
+			},
+			expected: `{
+				"patients": [{
+					"age": 39
+				}]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name:  "invalid reference_time",
+			input: []map[string]interface{}{},
+			config: &models.Config{
+				RootName:      "patients",
+				ReferenceTime: "29/01/2025",
+			},
+			expectedErr: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -546,3 +1088,364 @@ func TestConvertToJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTime(t *testing.T) {
+	actual, err := ParseTime("2025-01-29")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2025, time.January, 29, 0, 0, 0, 0, time.UTC), actual)
+
+	actual, err = ParseTime("2025-01-29T08:30:00-05:00")
+	require.NoError(t, err)
+	require.True(t, actual.Equal(time.Date(2025, time.January, 29, 13, 30, 0, 0, time.UTC)))
+
+	_, err = ParseTime("yesterday")
+	require.Error(t, err)
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputFilePath string
+		config        *models.Config
+		expected      string
+		expectedErr   bool
+	}{
+		{
+			name:          "multiple records",
+			inputFilePath: "../test/testdata/basicpatient/multiple_patients.xml",
+			config: &models.Config{
+				RootName: "patients",
+				Mappings: map[string]models.Mapping{
+					"ID": {Field: "id"},
+				},
+				Transformations: map[string]models.Transformation{
+					"name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"FirstName", "LastName"},
+							Extras: map[string]interface{}{"separator": " "},
+						},
+					},
+				},
+			},
+			expected: `{
+				"patients": [
+					{"id": 12345, "name": "Charlotte Taylor"},
+					{"id": 53425, "name": "Jane Doe"}
+				]
+			}`,
+			expectedErr: false,
+		},
+		{
+			name:          "no records",
+			inputFilePath: "../test/testdata/recordpath/envelope.xml",
+			config: &models.Config{
+				RootName:   "patients",
+				RecordPath: "Export/Patient",
+			},
+			expected:    `{"patients": []}`,
+			expectedErr: false,
+		},
+		{
+			name:          "invalid xml",
+			inputFilePath: "../test/testdata/basicpatient/invalid_xml.xml",
+			config: &models.Config{
+				RootName: "patients",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input, err := os.Open(test.inputFilePath)
+			require.NoError(t, err)
+			defer input.Close()
+
+			var output bytes.Buffer
+			err = Stream(input, &output, test.config)
+
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				var unmarshalledActual map[string]interface{}
+				var unmarshalledExpected map[string]interface{}
+				require.NoError(t, json.Unmarshal(output.Bytes(), &unmarshalledActual))
+				require.NoError(t, json.Unmarshal([]byte(test.expected), &unmarshalledExpected))
+				require.Equal(t, unmarshalledExpected, unmarshalledActual)
+			}
+		})
+	}
+}
+
+func TestRecordDecoderMaxDepth(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		recordPath  string
+		maxDepth    int
+		expectedErr bool
+	}{
+		{
+			name:        "at the limit",
+			input:       "<Patients><Patient><Gender>M</Gender></Patient></Patients>",
+			maxDepth:    3,
+			expectedErr: false,
+		},
+		{
+			name:        "past the limit",
+			input:       "<Patients><Patient><Address><Street>123 Havoc Way</Street></Address></Patient></Patients>",
+			maxDepth:    3,
+			expectedErr: true,
+		},
+		{
+			name:        "record path at the limit",
+			input:       "<Export><Patients><Patient><Gender>M</Gender></Patient></Patients></Export>",
+			recordPath:  "Export/Patients/Patient",
+			maxDepth:    4,
+			expectedErr: false,
+		},
+		{
+			name:        "outside of a record past the limit",
+			input:       "<Export><Patients><Patient><Gender>M</Gender></Patient></Patients></Export>",
+			recordPath:  "Export/Patients/Patient",
+			maxDepth:    2,
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := NewRecordDecoder(strings.NewReader(test.input), &models.Config{RecordPath: test.recordPath})
+			decoder.SetMaxDepth(test.maxDepth)
+
+			_, err := decoder.Next()
+			if test.expectedErr {
+				require.ErrorIs(t, err, ErrMaxDepth)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRecordDecoderTypeHints(t *testing.T) {
+	input := `<Patients>
+		<Patient ID="12345"><Visits>2</Visits></Patient>
+		<Patient ID="abc"><Visits>none</Visits></Patient>
+		<Patient ID="53425"><Visits>3</Visits></Patient>
+	</Patients>`
+	decoder := NewRecordDecoder(strings.NewReader(input), &models.Config{
+		Types: models.Types{Hints: map[string]string{"@ID": "int", "Visits": "int"}},
+	})
+
+	record, err := decoder.Next()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"@ID": 12345, "Visits": 2}, record)
+
+	// the first value that does not match its hint fails the record, which is read to its end
+	_, err = decoder.Next()
+	var recordErr *RecordError
+	require.ErrorAs(t, err, &recordErr)
+	require.Equal(t, 1, recordErr.Index)
+	require.ErrorIs(t, err, ErrTypeHint)
+	require.ErrorContains(t, err, `@ID "abc" is not int`)
+
+	record, err = decoder.Next()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"@ID": 53425, "Visits": 3}, record)
+
+	_, err = decoder.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestFieldOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		config      *models.Config
+		expected    map[string]interface{}
+		expectedErr error
+	}{
+		{
+			name:   "mapping uses default",
+			record: map[string]interface{}{"@ID": 12345},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"ID":     {Field: "id"},
+					"Gender": {Field: "gender", FieldOptions: models.FieldOptions{Default: "U"}},
+				},
+			},
+			expected:    map[string]interface{}{"id": 12345, "gender": "U"},
+			expectedErr: nil,
+		},
+		{
+			name:   "mapping uses first coalesce field with a value",
+			record: map[string]interface{}{"HomePhone": "", "WorkPhone": "555-555-5555"},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"MobilePhone": {Field: "phone", FieldOptions: models.FieldOptions{
+						Coalesce: []string{"HomePhone", "WorkPhone"},
+						Default:  "none",
+					}},
+				},
+			},
+			expected:    map[string]interface{}{"phone": "555-555-5555"},
+			expectedErr: nil,
+		},
+		{
+			name:   "mapping with value ignores options",
+			record: map[string]interface{}{"MobilePhone": "123-456-7890", "WorkPhone": "555-555-5555"},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"MobilePhone": {Field: "phone", FieldOptions: models.FieldOptions{
+						Coalesce: []string{"WorkPhone"},
+						Required: true,
+					}},
+				},
+			},
+			expected:    map[string]interface{}{"phone": "123-456-7890"},
+			expectedErr: nil,
+		},
+		{
+			name:   "missing optional mapping is left out",
+			record: map[string]interface{}{},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"Gender": {Field: "gender"},
+				},
+			},
+			expected:    map[string]interface{}{},
+			expectedErr: nil,
+		},
+		{
+			name:   "empty mapping with null-if-empty is written as null",
+			record: map[string]interface{}{"Race": ""},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"Race": {Field: "race", FieldOptions: models.FieldOptions{OutputType: "null-if-empty"}},
+				},
+			},
+			expected:    map[string]interface{}{"race": nil},
+			expectedErr: nil,
+		},
+		{
+			name:   "missing mapping with null-if-empty is left out",
+			record: map[string]interface{}{},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"Race": {Field: "race", FieldOptions: models.FieldOptions{OutputType: "null-if-empty"}},
+				},
+			},
+			expected:    map[string]interface{}{},
+			expectedErr: nil,
+		},
+		{
+			name:   "missing required mapping",
+			record: map[string]interface{}{"FirstName": "John"},
+			config: &models.Config{
+				Mappings: map[string]models.Mapping{
+					"ID": {Field: "id", FieldOptions: models.FieldOptions{Required: true}},
+				},
+			},
+			expectedErr: ErrRequiredField,
+		},
+		{
+			name:   "transformation uses default",
+			record: map[string]interface{}{},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"FirstName", "LastName"},
+						},
+						FieldOptions: models.FieldOptions{Default: "Unknown"},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"name": "Unknown"},
+			expectedErr: nil,
+		},
+		{
+			name:   "transformation uses coalesce field",
+			record: map[string]interface{}{"Code": "X", "Description": "Other"},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"gender": {
+						Type: "lookup",
+						Params: models.Params{
+							Fields: []string{"Code"},
+							Extras: map[string]interface{}{"table": map[string]interface{}{"M": "Male"}},
+						},
+						FieldOptions: models.FieldOptions{Coalesce: []string{"Description"}},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"gender": "Other"},
+			expectedErr: nil,
+		},
+		{
+			name:   "transformation without value is still written",
+			record: map[string]interface{}{},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"FirstName", "LastName"},
+						},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"name": ""},
+			expectedErr: nil,
+		},
+		{
+			name:   "missing required transformation",
+			record: map[string]interface{}{},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"FirstName", "LastName"},
+						},
+						FieldOptions: models.FieldOptions{Required: true},
+					},
+				},
+			},
+			expectedErr: ErrRequiredField,
+		},
+		{
+			name:   "required concat with some fields missing",
+			record: map[string]interface{}{"FirstName": "John"},
+			config: &models.Config{
+				Transformations: map[string]models.Transformation{
+					"name": {
+						Type: "concat",
+						Params: models.Params{
+							Fields: []string{"FirstName", "LastName"},
+							Extras: map[string]interface{}{"separator": " "},
+						},
+						FieldOptions: models.FieldOptions{Required: true},
+					},
+				},
+			},
+			expected:    map[string]interface{}{"name": "John"},
+			expectedErr: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := TransformRecord(test.record, test.config, Options{})
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				var fieldErr *FieldError
+				require.ErrorAs(t, err, &fieldErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"havocai-assignment/internal/fieldpath"
+)
+
+// attrPrefix marks the keys of attributes within a parsed element, e.g. "@ID".
+const attrPrefix = "@"
+
+// isPath reports whether a field is a path expression rather than a bare element or attribute name.
+func isPath(field string) bool {
+	return fieldpath.IsPath(field)
+}
+
+// resolvePath looks up a path expression relative to a record. Repeated elements without an
+// index resolve to their first occurrence when the path continues below them. A path that cannot
+// be parsed is never found; such paths are reported when the config is validated.
+func resolvePath(field string, record map[string]interface{}) (interface{}, bool) {
+	segments, err := fieldpath.Parse(field)
+	if err != nil {
+		return nil, false
+	}
+
+	var current interface{} = record
+	for _, segment := range segments {
+		if list, ok := current.([]interface{}); ok {
+			if len(list) == 0 {
+				return nil, false
+			}
+			current = list[0]
+		}
+
+		element, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = element[segment.Name]
+		if !ok {
+			return nil, false
+		}
+
+		if segment.Index > 0 {
+			current, ok = selectIndex(current, segment.Index)
+			if !ok {
+				return nil, false
+			}
+		}
+	}
+	return current, true
+}
+
+// selectIndex returns the element at a 1-based index. A single element only has index 1.
+func selectIndex(value interface{}, index int) (interface{}, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return value, index == 1
+	}
+	if index > len(list) {
+		return nil, false
+	}
+	return list[index-1], true
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePath(t *testing.T) {
+	record := map[string]interface{}{
+		"@ID": 12345,
+		"Address": map[string]interface{}{
+			"Street": "123 Havoc Way",
+		},
+		"Provider": map[string]interface{}{
+			"@ID":  999,
+			"Name": "Dr. Smith",
+		},
+		"Phone": []interface{}{"123-456-7890", "555-555-5555"},
+		"Encounter": []interface{}{
+			map[string]interface{}{"Date": "2025-01-05"},
+			map[string]interface{}{"Date": "2025-01-29"},
+		},
+		"Allergy": []interface{}{},
+	}
+
+	tests := []struct {
+		name       string
+		field      string
+		expected   interface{}
+		expectedOk bool
+	}{
+		{
+			name:       "record attribute",
+			field:      "@ID",
+			expected:   12345,
+			expectedOk: true,
+		},
+		{
+			name:       "nested element",
+			field:      "Address/Street",
+			expected:   "123 Havoc Way",
+			expectedOk: true,
+		},
+		{
+			name:       "attribute of nested element",
+			field:      "Provider/@ID",
+			expected:   999,
+			expectedOk: true,
+		},
+		{
+			name:       "indexed repeated element",
+			field:      "Phone[2]",
+			expected:   "555-555-5555",
+			expectedOk: true,
+		},
+		{
+			name:       "index out of range",
+			field:      "Phone[3]",
+			expected:   nil,
+			expectedOk: false,
+		},
+		{
+			name:       "index 1 of single element",
+			field:      "Address[1]/Street",
+			expected:   "123 Havoc Way",
+			expectedOk: true,
+		},
+		{
+			name:       "repeated element without index uses first",
+			field:      "Encounter/Date",
+			expected:   "2025-01-05",
+			expectedOk: true,
+		},
+		{
+			name:       "indexed repeated element with child",
+			field:      "Encounter[2]/Date",
+			expected:   "2025-01-29",
+			expectedOk: true,
+		},
+		{
+			name:       "empty repeated element",
+			field:      "Allergy/Name",
+			expected:   nil,
+			expectedOk: false,
+		},
+		{
+			name:       "invalid path",
+			field:      "Phone[x",
+			expected:   nil,
+			expectedOk: false,
+		},
+		{
+			name:       "missing element",
+			field:      "Insurance/@ID",
+			expected:   nil,
+			expectedOk: false,
+		},
+		{
+			name:       "path below a value",
+			field:      "Address/Street/Name",
+			expected:   nil,
+			expectedOk: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, ok := resolvePath(test.field, record)
+			require.Equal(t, test.expectedOk, ok)
+			require.Equal(t, test.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/models"
+	"sort"
+	"sync"
+)
+
+// Transformer produces the value of an output field from a record. The transformation holds
+// the params configured for the output field.
+type Transformer interface {
+	Transform(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error)
+}
+
+// TransformerFunc allows an ordinary function to be used as a Transformer.
+type TransformerFunc func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error)
+
+func (f TransformerFunc) Transform(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+	return f(record, transformation, opts)
+}
+
+// SchemaTransformer is a Transformer that describes the params it accepts. Register passes its
+// schema on to config validation, so that configs using the type are checked before any input
+// is converted.
+type SchemaTransformer interface {
+	Transformer
+	Schema() models.Schema
+}
+
+// WithSchema returns a SchemaTransformer that transforms with the transformer and is validated
+// against the schema.
+func WithSchema(transformer Transformer, schema models.Schema) SchemaTransformer {
+	return schemaTransformer{Transformer: transformer, schema: schema}
+}
+
+type schemaTransformer struct {
+	Transformer
+	schema models.Schema
+}
+
+func (t schemaTransformer) Schema() models.Schema {
+	return t.schema
+}
+
+var (
+	registryMu   sync.RWMutex
+	transformers = make(map[string]Transformer)
+)
+
+func init() {
+	Register("concat", WithSchema(TransformerFunc(func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+		return concatTransformation(record, transformation)
+	}), concatSchema))
+	Register("calculate", WithSchema(TransformerFunc(calculateTransformation), calculateSchema))
+	Register("format", WithSchema(TransformerFunc(formatTransformation), formatSchema))
+	Register("exists", WithSchema(TransformerFunc(func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+		return existsTransformation(record, transformation)
+	}), existsSchema))
+	Register("if", WithSchema(TransformerFunc(ifTransformation), ifSchema))
+	Register("lookup", WithSchema(TransformerFunc(func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+		return lookupTransformation(record, transformation)
+	}), lookupSchema))
+	Register("string", WithSchema(TransformerFunc(func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+		return stringTransformation(record, transformation)
+	}), stringSchema))
+}
+
+// Register makes a transformation type available to configs under the given name. It is meant
+// to be called from an init function and panics if the name is already registered or the
+// transformer is nil. A SchemaTransformer is validated against its schema. Other transformers are
+// validated with a schema that accepts any params, unless a schema has been registered for the
+// name with models.RegisterSchema.
+func Register(name string, transformer Transformer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if transformer == nil {
+		panic(fmt.Sprintf("parser: transformer for %q is nil", name))
+	}
+	if _, exists := transformers[name]; exists {
+		panic(fmt.Sprintf("parser: transformation type %q is already registered", name))
+	}
+	transformers[name] = transformer
+
+	if t, ok := transformer.(SchemaTransformer); ok {
+		models.RegisterSchema(name, t.Schema())
+	} else if _, ok := models.LookupSchema(name); !ok {
+		models.RegisterSchema(name, models.Schema{AllowUnknownExtras: true})
+	}
+}
+
+// unregister removes a transformation type, so that tests can register types of their own
+// without affecting other tests.
+func unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(transformers, name)
+}
+
+// Lookup returns the transformer registered under the given name.
+func Lookup(name string) (Transformer, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	transformer, ok := transformers[name]
+	return transformer, ok
+}
+
+// Types returns the names of all registered transformation types in sorted order.
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(transformers))
+	for name := range transformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
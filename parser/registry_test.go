@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	Register("test_upper", TransformerFunc(func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+		val, found := getFieldValue(transformation.Params.Fields[0], record, transformation.Params.Extras)
+		if !found {
+			return nil, fmt.Errorf("field %v not found", transformation.Params.Fields[0])
+		}
+		return strings.ToUpper(fmt.Sprintf("%v", val)), nil
+	}))
+	t.Cleanup(func() { unregister("test_upper") })
+
+	transformer, ok := Lookup("test_upper")
+	require.True(t, ok)
+	require.NotNil(t, transformer)
+	require.Contains(t, Types(), "test_upper")
+
+	cfg := &models.Config{
+		Transformations: map[string]models.Transformation{
+			"last_name": {
+				Type: "test_upper",
+				Params: models.Params{
+					Fields: []string{"LastName"},
+				},
+			},
+		},
+	}
+	actual, err := TransformRecord(map[string]interface{}{"LastName": "Doe"}, cfg, Options{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"last_name": "DOE"}, actual)
+
+	// registered types are accepted by config validation with any params
+	cfg.RootName = "patients"
+	transformation := cfg.Transformations["last_name"]
+	transformation.Params.Extras = map[string]interface{}{"locale": "en"}
+	cfg.Transformations["last_name"] = transformation
+	require.NoError(t, cfg.Validate())
+
+	require.Panics(t, func() {
+		Register("test_upper", transformer)
+	})
+	require.Panics(t, func() {
+		Register("test_nil", nil)
+	})
+}
+
+func TestRegisterWithSchema(t *testing.T) {
+	Register("test_trim", WithSchema(TransformerFunc(func(record map[string]interface{}, transformation models.Transformation, opts Options) (interface{}, error) {
+		val, _ := getFieldValue(transformation.Params.Fields[0], record, transformation.Params.Extras)
+		return strings.TrimSpace(fmt.Sprintf("%v", val)), nil
+	}), models.Schema{
+		MinFields: 1,
+		MaxFields: 1,
+		Extras: map[string]models.ExtraSchema{
+			"cutset": {Kind: models.StringValue},
+		},
+	}))
+	t.Cleanup(func() { unregister("test_trim") })
+
+	cfg := &models.Config{
+		RootName: "patients",
+		Transformations: map[string]models.Transformation{
+			"name": {
+				Type: "test_trim",
+				Params: models.Params{
+					Fields: []string{"FirstName", "LastName"},
+					Extras: map[string]interface{}{"cutset": 1},
+				},
+			},
+		},
+	}
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, cfg.Validate(), &validationErr)
+	require.Equal(t, []models.Problem{
+		{Path: "transformations.name.params.fields", Message: "requires exactly 1 field"},
+		{Path: "transformations.name.params.extras.cutset", Message: "must be a string"},
+	}, validationErr.Problems)
+}
+
+func TestBuiltinTypesRegistered(t *testing.T) {
+	for _, name := range []string{"concat", "calculate"} {
+		_, ok := Lookup(name)
+		require.True(t, ok, "expected %v to be registered", name)
+	}
+}
+
+func TestUnknownTransformationType(t *testing.T) {
+	cfg := &models.Config{
+		Transformations: map[string]models.Transformation{
+			"name": {
+				Type: "concatenate",
+			},
+		},
+	}
+	_, err := TransformRecord(map[string]interface{}{}, cfg, Options{})
+	require.ErrorContains(t, err, `unknown transformation type "concatenate"`)
+}
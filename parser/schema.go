@@ -0,0 +1,225 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"havocai-assignment/internal/jsonpath"
+	"havocai-assignment/models"
+	"regexp"
+)
+
+// The schemas of the built-in transformation types, which Register passes on to config
+// validation along with each transformer.
+var (
+	concatSchema = models.Schema{
+		MinFields: 1,
+		Extras: map[string]models.ExtraSchema{
+			"separator":       {Kind: models.StringValue},
+			"fail_on_missing": {Kind: models.BoolValue},
+		},
+	}
+	calculateSchema = models.Schema{
+		MinFields: 1,
+		Extras: models.WithDateExtras(map[string]models.ExtraSchema{
+			"operation": {Kind: models.StringValue, Required: true, OneOf: []string{
+				"add", "subtract", "multiply", "divide", "modulo", "time_difference",
+			}},
+			"unit": {Kind: models.StringValue, OneOf: []string{
+				"years", "months", "years_months", "years_months_days", "weeks", "days", "hours",
+				"minutes", "seconds", "milliseconds", "microseconds", "nanoseconds",
+			}},
+			"adjust_if_day_not_passed": {Kind: models.BoolValue},
+			"round_to_int":             {Kind: models.BoolValue},
+			"decimal_precision":        {Kind: models.IntegerValue},
+		}),
+		Check: checkCalculate,
+	}
+	formatSchema = models.Schema{
+		MinFields: 1,
+		MaxFields: 1,
+		Extras: models.WithDateExtras(map[string]models.ExtraSchema{
+			"mode": {Kind: models.StringValue, Required: true, OneOf: []string{
+				"date", "number", "currency", "pad", "phone",
+			}},
+			"output_format":       {Kind: models.StringValue},
+			"decimal_precision":   {Kind: models.IntegerValue},
+			"thousands_separator": {Kind: models.StringValue},
+			"decimal_separator":   {Kind: models.StringValue},
+			"symbol":              {Kind: models.StringValue},
+			"width":               {Kind: models.IntegerValue},
+			"fill":                {Kind: models.StringValue},
+			"align":               {Kind: models.StringValue, OneOf: []string{"left", "right"}},
+			"pattern":             {Kind: models.StringValue},
+		}),
+		Check: checkFormat,
+	}
+	existsSchema = models.Schema{
+		MinFields: 1,
+	}
+	ifSchema = models.Schema{
+		Extras: map[string]models.ExtraSchema{
+			"condition": {Kind: models.ObjectValue, Required: true},
+			"then":      {Kind: models.AnyValue},
+			"else":      {Kind: models.AnyValue},
+		},
+		Check: checkIf,
+	}
+	lookupSchema = models.Schema{
+		MinFields: 1,
+		MaxFields: 1,
+		Extras: map[string]models.ExtraSchema{
+			"table":           {Kind: models.ObjectValue},
+			"table_file":      {Kind: models.StringValue},
+			"default":         {Kind: models.AnyValue},
+			"fail_on_missing": {Kind: models.BoolValue},
+		},
+		Check: checkLookup,
+	}
+	stringSchema = models.Schema{
+		MinFields: 1,
+		MaxFields: 1,
+		Extras: map[string]models.ExtraSchema{
+			"operation": {Kind: models.StringValue, Required: true, OneOf: []string{
+				"extract", "replace", "split", "upper", "lower", "title", "trim", "substring", "normalize_space",
+			}},
+			"pattern":     {Kind: models.StringValue},
+			"group":       {Kind: models.AnyValue},
+			"replacement": {Kind: models.StringValue},
+			"separator":   {Kind: models.StringValue},
+			"index":       {Kind: models.IntegerValue},
+			"cutset":      {Kind: models.StringValue},
+			"start":       {Kind: models.IntegerValue},
+			"length":      {Kind: models.IntegerValue},
+		},
+		Check: checkString,
+	}
+)
+
+func checkCalculate(params models.Params) []models.Problem {
+	problems := models.CheckTimeZones("extras", params.Extras)
+	if params.Extras["operation"] == "time_difference" && len(params.Fields) != 2 {
+		problems = append(problems, models.Problem{Path: "fields", Message: "time_difference requires exactly 2 fields"})
+	}
+	// whole calendar years and months are never rounded
+	switch unit, _ := params.Extras["unit"].(string); unit {
+	case "years", "months", "years_months", "years_months_days":
+		for _, extra := range []string{"decimal_precision", "round_to_int"} {
+			if _, ok := params.Extras[extra]; ok {
+				problems = append(problems, models.Problem{Path: "extras." + extra, Message: fmt.Sprintf("cannot be used with unit %q, which counts whole calendar units", unit)})
+			}
+		}
+	}
+	return problems
+}
+
+func checkFormat(params models.Params) []models.Problem {
+	problems := models.CheckTimeZones("extras", params.Extras)
+	required := map[string]string{
+		"date":  "output_format",
+		"pad":   "width",
+		"phone": "pattern",
+	}
+	mode, _ := params.Extras["mode"].(string)
+	if extra, ok := required[mode]; ok {
+		if _, ok := params.Extras[extra]; !ok {
+			problems = append(problems, models.Problem{Path: "extras." + extra, Message: fmt.Sprintf("is required by mode %q", mode)})
+		}
+	}
+	return problems
+}
+
+func checkLookup(params models.Params) []models.Problem {
+	_, hasTable := params.Extras["table"]
+	_, hasFile := params.Extras["table_file"]
+	if !hasTable && !hasFile {
+		return []models.Problem{{Path: "extras", Message: "lookup requires a table or table_file"}}
+	}
+	return nil
+}
+
+func checkString(params models.Params) []models.Problem {
+	operation, _ := params.Extras["operation"].(string)
+	var problems []models.Problem
+	switch operation {
+	case "extract", "replace":
+		pattern, ok := params.Extras["pattern"].(string)
+		if !ok {
+			problems = append(problems, models.Problem{Path: "extras.pattern", Message: fmt.Sprintf("is required by operation %q", operation)})
+		} else if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, models.Problem{Path: "extras.pattern", Message: fmt.Sprintf("invalid regular expression: %v", err)})
+		}
+	case "split":
+		if _, ok := params.Extras["separator"]; !ok {
+			problems = append(problems, models.Problem{Path: "extras.separator", Message: `is required by operation "split"`})
+		}
+	}
+	return problems
+}
+
+// conditionPredicates are the predicates that an if condition can be built from.
+var conditionPredicates = map[string]bool{
+	"exists": true, "equals": true, "compare": true, "matches": true, "and": true, "or": true, "not": true,
+}
+
+func checkIf(params models.Params) []models.Problem {
+	var problems []models.Problem
+	// a condition that is missing or not an object is reported by the extras schema
+	if condition, ok := params.Extras["condition"].(map[string]interface{}); ok {
+		problems = checkCondition("extras.condition", condition)
+	}
+	for _, branch := range []string{"then", "else"} {
+		result, ok := params.Extras[branch].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		nested, ok := result["transformation"]
+		if !ok {
+			continue
+		}
+
+		path := "extras." + branch + ".transformation"
+		data, err := json.Marshal(nested)
+		if err != nil {
+			problems = append(problems, models.Problem{Path: path, Message: err.Error()})
+			continue
+		}
+		var transformation models.Transformation
+		if err := json.Unmarshal(data, &transformation); err != nil {
+			problems = append(problems, models.Problem{Path: path, Message: "must be a transformation"})
+			continue
+		}
+		problems = append(problems, models.ValidateTransformation(path, transformation)...)
+	}
+	return problems
+}
+
+// checkCondition checks that a condition is built from known predicates. The arguments of each
+// predicate are checked when the condition is evaluated.
+func checkCondition(path string, condition interface{}) []models.Problem {
+	predicate, ok := condition.(map[string]interface{})
+	if !ok || len(predicate) != 1 {
+		return []models.Problem{{Path: path, Message: "must be an object with a single predicate"}}
+	}
+
+	for name, args := range predicate {
+		if !conditionPredicates[name] {
+			return []models.Problem{{Path: path, Message: fmt.Sprintf("unsupported condition %q", name)}}
+		}
+
+		switch name {
+		case "not":
+			return checkCondition(jsonpath.Join(path, name), args)
+		case "and", "or":
+			conditions, ok := args.([]interface{})
+			if !ok {
+				return []models.Problem{{Path: jsonpath.Join(path, name), Message: "must be a list of conditions"}}
+			}
+			var problems []models.Problem
+			for i, c := range conditions {
+				problems = append(problems, checkCondition(fmt.Sprintf("%v[%d]", jsonpath.Join(path, name), i), c)...)
+			}
+			return problems
+		}
+	}
+	return nil
+}
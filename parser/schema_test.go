@@ -0,0 +1,218 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinSchemas(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           models.Config
+		expectedProblems []models.Problem
+	}{
+		{
+			name: "valid config",
+			config: models.Config{
+				RootName: "patients",
+				Mappings: map[string]models.Mapping{
+					"@ID": {Field: "id", FieldOptions: models.FieldOptions{OutputType: "string"}},
+				},
+				Transformations: map[string]models.Transformation{
+					"age": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"DateOfBirth", "CurrentTime"},
+							Extras: map[string]interface{}{
+								"operation": "time_difference",
+								"format":    []interface{}{"2006-01-02", "01/02/2006"},
+								"timezone":  "America/New_York",
+								"unit":      "years",
+							},
+						},
+					},
+					"age_in_months": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"$age", "months_per_year"},
+							Extras: map[string]interface{}{
+								"operation":       "multiply",
+								"months_per_year": float64(12),
+							},
+						},
+					},
+					"mrn": {
+						Steps: []models.Transformation{
+							{Type: "string", Params: models.Params{
+								Fields: []string{"MRN"},
+								Extras: map[string]interface{}{"operation": "trim"},
+							}},
+							{Type: "format", Params: models.Params{
+								Fields: []string{"$"},
+								Extras: map[string]interface{}{"mode": "pad", "width": float64(8)},
+							}},
+						},
+					},
+					"deceased": {
+						Type: "if",
+						Params: models.Params{
+							Extras: map[string]interface{}{
+								"condition": map[string]interface{}{
+									"and": []interface{}{
+										map[string]interface{}{"exists": "DateOfDeath"},
+										map[string]interface{}{"not": map[string]interface{}{"equals": map[string]interface{}{"field": "Status", "value": "alive"}}},
+									},
+								},
+								"then": true,
+								"else": false,
+							},
+						},
+					},
+				},
+				ReferenceTime: "2025-01-29",
+				Types: models.Types{
+					Inference: "strict",
+					Hints:     map[string]string{"Zip": "string"},
+				},
+			},
+			expectedProblems: nil,
+		},
+		{
+			name: "fields and extras",
+			config: models.Config{
+				RootName: "patients",
+				Transformations: map[string]models.Transformation{
+					"age": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"DateOfBirth"},
+							Extras: map[string]interface{}{
+								"operation":         "time_difference",
+								"unit":              "decades",
+								"decimal_precision": "2",
+								"round_to_int":      "true",
+								"timezone":          "Mars/Olympus_Mons",
+								"seperator":         " ",
+							},
+						},
+					},
+					"name": {
+						Type:   "concat",
+						Params: models.Params{Fields: []string{"FirstName", ""}},
+					},
+					"gender": {
+						Type:   "lookup",
+						Params: models.Params{Fields: []string{"Gender", "Sex"}},
+					},
+				},
+			},
+			expectedProblems: []models.Problem{
+				{Path: "transformations.age.params.extras.decimal_precision", Message: "must be a whole number"},
+				{Path: "transformations.age.params.extras.round_to_int", Message: "must be true or false"},
+				{Path: "transformations.age.params.extras.seperator", Message: "is not a supported extra"},
+				{Path: "transformations.age.params.extras.unit", Message: `unsupported value "decades", must be one of: years, months, years_months, years_months_days, weeks, days, hours, minutes, seconds, milliseconds, microseconds, nanoseconds`},
+				{Path: "transformations.age.params.extras.timezone", Message: `unknown time zone "Mars/Olympus_Mons"`},
+				{Path: "transformations.age.params.fields", Message: "time_difference requires exactly 2 fields"},
+				{Path: "transformations.gender.params.fields", Message: "requires exactly 1 field"},
+				{Path: "transformations.gender.params.extras", Message: "lookup requires a table or table_file"},
+				{Path: "transformations.name.params.fields[1]", Message: "must not be empty"},
+			},
+		},
+		{
+			name: "required and unused extras",
+			config: models.Config{
+				RootName: "patients",
+				Transformations: map[string]models.Transformation{
+					"age": {
+						Type: "calculate",
+						Params: models.Params{
+							Fields: []string{"DateOfBirth", "CurrentTime"},
+							Extras: map[string]interface{}{
+								"operation":         "time_difference",
+								"unit":              "months",
+								"decimal_precision": float64(2),
+								"round_to_int":      true,
+							},
+						},
+					},
+					"birth_date": {
+						Type: "format",
+						Params: models.Params{
+							Fields: []string{"DateOfBirth"},
+							Extras: map[string]interface{}{"mode": "date"},
+						},
+					},
+					"total": {
+						Type:   "calculate",
+						Params: models.Params{Fields: []string{"A", "B"}},
+					},
+					"zip": {
+						Type: "string",
+						Params: models.Params{
+							Fields: []string{"Zip"},
+							Extras: map[string]interface{}{"operation": "extract", "pattern": "([0-9]{5}"},
+						},
+					},
+				},
+			},
+			expectedProblems: []models.Problem{
+				{Path: "transformations.age.params.extras.decimal_precision", Message: `cannot be used with unit "months", which counts whole calendar units`},
+				{Path: "transformations.age.params.extras.round_to_int", Message: `cannot be used with unit "months", which counts whole calendar units`},
+				{Path: "transformations.birth_date.params.extras.output_format", Message: `is required by mode "date"`},
+				{Path: "transformations.total.params.extras.operation", Message: "is required"},
+				{Path: "transformations.zip.params.extras.pattern", Message: "invalid regular expression: error parsing regexp: missing closing ): `([0-9]{5}`"},
+			},
+		},
+		{
+			name: "steps and nested transformations",
+			config: models.Config{
+				RootName: "patients",
+				Transformations: map[string]models.Transformation{
+					"mrn": {
+						Type: "string",
+						Steps: []models.Transformation{
+							{Type: "string", Params: models.Params{Fields: []string{"MRN"}}},
+						},
+					},
+					"status": {
+						Type: "if",
+						Params: models.Params{
+							Extras: map[string]interface{}{
+								"condition": map[string]interface{}{
+									"or": []interface{}{
+										map[string]interface{}{"exists": "DateOfDeath"},
+										map[string]interface{}{"present": "Status"},
+									},
+								},
+								"then": map[string]interface{}{
+									"transformation": map[string]interface{}{"type": "concat"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedProblems: []models.Problem{
+				{Path: "transformations.mrn.type", Message: "cannot be used together with steps"},
+				{Path: "transformations.mrn.steps[0].params.extras.operation", Message: "is required"},
+				{Path: "transformations.status.params.extras.condition.or[1]", Message: `unsupported condition "present"`},
+				{Path: "transformations.status.params.extras.then.transformation.params.fields", Message: "requires at least 1 field"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.Validate()
+			if test.expectedProblems == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *models.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, test.expectedProblems, validationErr.Problems)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"havocai-assignment/models"
+	"io"
+)
+
+// StreamOptions controls how StreamWith converts records. The zero value writes a single JSON
+// document under the config's root and stops at the first record that cannot be converted.
+type StreamOptions struct {
+	// NewWriter creates the writer that transformed records are written to. When nil, records are
+	// written with a JSONWriter under the config's root.
+	NewWriter func(w io.Writer) (RecordWriter, error)
+	// OnRecordError is called for every record that cannot be transformed. When it returns nil the
+	// record is left out of the output and the stream carries on, otherwise the stream stops with
+	// the error it returns. When nil, the stream stops at the first such record.
+	OnRecordError func(err *RecordError) error
+	// MaxRecords fails the stream with ErrMaxRecords once the input has more than MaxRecords
+	// records. A limit of 0 means no limit.
+	MaxRecords int
+	// MaxDepth limits how deeply elements may be nested, see RecordDecoder.SetMaxDepth.
+	MaxDepth int
+}
+
+// Stream converts the XML read from r into JSON written to w one record at a time: each record
+// is transformed and written as soon as it has been parsed, so memory use does not grow with the
+// size of the input.
+func Stream(r io.Reader, w io.Writer, cfg *models.Config) error {
+	transformer, err := NewRecordTransformer(cfg, Options{})
+	if err != nil {
+		return err
+	}
+	return StreamWith(context.Background(), r, w, transformer, StreamOptions{})
+}
+
+// StreamWith converts records the same way as Stream, using the config of the transformer, with
+// options for the output, errors and limits. Cancelling ctx stops the conversion between records.
+func StreamWith(ctx context.Context, r io.Reader, w io.Writer, transformer *RecordTransformer, opts StreamOptions) error {
+	cfg := transformer.cfg
+	buffered := bufio.NewWriter(w)
+	var writer RecordWriter
+	var err error
+	if opts.NewWriter != nil {
+		writer, err = opts.NewWriter(buffered)
+	} else {
+		writer, err = NewJSONWriter(buffered, cfg.RootName)
+	}
+	if err != nil {
+		return err
+	}
+
+	decoder := NewRecordDecoder(r, cfg)
+	decoder.SetMaxDepth(opts.MaxDepth)
+
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// a record whose values do not match their type hints is reported the same way as a
+		// record that cannot be transformed
+		record, err := decoder.Next()
+		var recordErr *RecordError
+		if err != nil && !errors.As(err, &recordErr) {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("error parsing XML: %w", err)
+		}
+
+		if opts.MaxRecords > 0 && i >= opts.MaxRecords {
+			return fmt.Errorf("input has more than %d records: %w", opts.MaxRecords, ErrMaxRecords)
+		}
+
+		var transformed map[string]interface{}
+		if recordErr == nil {
+			if transformed, err = transformer.Transform(record); err != nil {
+				recordErr = &RecordError{Index: i, Err: err}
+			}
+		}
+		if recordErr != nil {
+			if opts.OnRecordError == nil {
+				return recordErr
+			}
+			if err := opts.OnRecordError(recordErr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := writer.Write(transformed); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/models"
+	"strings"
+	"unicode"
+)
+
+// stringTransformation applies the string "operation" in extras to the value of a single field:
+// extract, replace, split, upper, lower, title, trim, substring or normalize_space.
+func stringTransformation(record map[string]interface{}, transformation models.Transformation) (interface{}, error) {
+	extras := transformation.Params.Extras
+	operation, ok := extras["operation"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid operation")
+	}
+
+	fields := transformation.Params.Fields
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("string requires exactly one field")
+	}
+
+	val, found := getFieldValue(fields[0], record, extras)
+	if !found {
+		return nil, fmt.Errorf("field %v not found in XML or extras", fields[0])
+	}
+	strVal := fmt.Sprintf("%v", val)
+
+	switch operation {
+	case "extract":
+		return extractString(strVal, extras)
+	case "replace":
+		return replaceString(strVal, extras)
+	case "split":
+		return splitString(strVal, extras)
+	case "upper":
+		return strings.ToUpper(strVal), nil
+	case "lower":
+		return strings.ToLower(strVal), nil
+	case "title":
+		return titleCase(strVal), nil
+	case "trim":
+		if cutset, ok := extras["cutset"].(string); ok {
+			return strings.Trim(strVal, cutset), nil
+		}
+		return strings.TrimSpace(strVal), nil
+	case "substring":
+		return substring(strVal, extras)
+	case "normalize_space":
+		return strings.Join(strings.Fields(strVal), " "), nil
+	default:
+		return nil, fmt.Errorf("unsupported operation: %v", operation)
+	}
+}
+
+// extractString returns a capture group of the first match of "pattern". The group is a number or
+// the name of a named group, and defaults to the first group, or the whole match when the
+// pattern has no groups. A value that does not match produces no value.
+func extractString(val string, extras map[string]interface{}) (interface{}, error) {
+	pattern, ok := extras["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("extract requires a pattern")
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	group := min(1, re.NumSubexp())
+	if name, ok := extras["group"].(string); ok {
+		group = re.SubexpIndex(name)
+		if group == -1 {
+			return nil, fmt.Errorf("pattern %q has no group named %v", pattern, name)
+		}
+	} else if index, ok, err := intExtra(extras, "group"); err != nil {
+		return nil, err
+	} else if ok {
+		group = index
+	}
+
+	if group < 0 || group > re.NumSubexp() {
+		return nil, fmt.Errorf("pattern %q has no group %d", pattern, group)
+	}
+
+	match := re.FindStringSubmatch(val)
+	if match == nil {
+		return nil, nil
+	}
+	return match[group], nil
+}
+
+// replaceString replaces every match of "pattern" with "replacement", which may refer to capture
+// groups as $1 or ${name}.
+func replaceString(val string, extras map[string]interface{}) (interface{}, error) {
+	pattern, ok := extras["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("replace requires a pattern")
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement, _ := extras["replacement"].(string)
+	return re.ReplaceAllString(val, replacement), nil
+}
+
+// splitString splits a value on "separator" and returns the part at "index", counting from 0.
+// A negative index counts from the end, so -1 is the last part. An index past the end produces
+// no value.
+func splitString(val string, extras map[string]interface{}) (interface{}, error) {
+	separator, ok := extras["separator"].(string)
+	if !ok {
+		return nil, fmt.Errorf("split requires a separator")
+	}
+	index, ok, err := intExtra(extras, "index")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("split requires an index")
+	}
+
+	parts := strings.Split(val, separator)
+	if index < 0 {
+		index += len(parts)
+	}
+	if index < 0 || index >= len(parts) {
+		return nil, nil
+	}
+	return strings.TrimSpace(parts[index]), nil
+}
+
+// substring returns "length" characters starting at "start", counting from 0. Without a length,
+// the rest of the value is returned.
+func substring(val string, extras map[string]interface{}) (interface{}, error) {
+	runes := []rune(val)
+
+	start, _, err := intExtra(extras, "start")
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("start must not be negative")
+	}
+	start = min(start, len(runes))
+
+	end := len(runes)
+	length, ok, err := intExtra(extras, "length")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if length < 0 {
+			return nil, fmt.Errorf("length must not be negative")
+		}
+		end = min(start+length, len(runes))
+	}
+	return string(runes[start:end]), nil
+}
+
+// titleCase upper cases the first letter of every word and lower cases the rest.
+func titleCase(val string) string {
+	runes := []rune(strings.ToLower(val))
+	startOfWord := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			if startOfWord {
+				runes[i] = unicode.ToUpper(r)
+			}
+			startOfWord = false
+			continue
+		}
+		startOfWord = unicode.IsSpace(r) || r == '-'
+	}
+	return string(runes)
+}
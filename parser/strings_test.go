@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringTransformation(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		extras      map[string]interface{}
+		expected    interface{}
+		expectedErr bool
+	}{
+		{
+			name:  "extract first group",
+			value: "Providence, RI",
+			extras: map[string]interface{}{
+				"operation": "extract",
+				"pattern":   `^(.+), (\w{2})$`,
+			},
+			expected:    "Providence",
+			expectedErr: false,
+		},
+		{
+			name:  "extract numbered group",
+			value: "Providence, RI",
+			extras: map[string]interface{}{
+				"operation": "extract",
+				"pattern":   `^(.+), (\w{2})$`,
+				"group":     2.0,
+			},
+			expected:    "RI",
+			expectedErr: false,
+		},
+		{
+			name:  "extract named group",
+			value: "Providence, RI",
+			extras: map[string]interface{}{
+				"operation": "extract",
+				"pattern":   `^(?P<city>.+), (?P<state>\w{2})$`,
+				"group":     "state",
+			},
+			expected:    "RI",
+			expectedErr: false,
+		},
+		{
+			name:  "extract whole match without groups",
+			value: "MRN: 00123",
+			extras: map[string]interface{}{
+				"operation": "extract",
+				"pattern":   `\d+`,
+			},
+			expected:    "00123",
+			expectedErr: false,
+		},
+		{
+			name:  "extract without match",
+			value: "Providence",
+			extras: map[string]interface{}{
+				"operation": "extract",
+				"pattern":   `^(.+), (\w{2})$`,
+			},
+			expected:    nil,
+			expectedErr: false,
+		},
+		{
+			name:  "extract unknown group",
+			value: "Providence, RI",
+			extras: map[string]interface{}{
+				"operation": "extract",
+				"pattern":   `^(.+), (\w{2})$`,
+				"group":     3,
+			},
+			expectedErr: true,
+		},
+		{
+			name:  "replace non digits",
+			value: "(888) 555-1234",
+			extras: map[string]interface{}{
+				"operation":   "replace",
+				"pattern":     `\D`,
+				"replacement": "",
+			},
+			expected:    "8885551234",
+			expectedErr: false,
+		},
+		{
+			name:  "replace with groups",
+			value: "Doe, John",
+			extras: map[string]interface{}{
+				"operation":   "replace",
+				"pattern":     `^(\w+), (\w+)$`,
+				"replacement": "$2 $1",
+			},
+			expected:    "John Doe",
+			expectedErr: false,
+		},
+		{
+			name:  "replace with invalid pattern",
+			value: "Doe, John",
+			extras: map[string]interface{}{
+				"operation": "replace",
+				"pattern":   `(`,
+			},
+			expectedErr: true,
+		},
+		{
+			name:  "split",
+			value: "Providence, RI",
+			extras: map[string]interface{}{
+				"operation": "split",
+				"separator": ",",
+				"index":     1.0,
+			},
+			expected:    "RI",
+			expectedErr: false,
+		},
+		{
+			name:  "split from end",
+			value: "123 Havoc Way",
+			extras: map[string]interface{}{
+				"operation": "split",
+				"separator": " ",
+				"index":     -1,
+			},
+			expected:    "Way",
+			expectedErr: false,
+		},
+		{
+			name:  "split index past end",
+			value: "Providence",
+			extras: map[string]interface{}{
+				"operation": "split",
+				"separator": ",",
+				"index":     1,
+			},
+			expected:    nil,
+			expectedErr: false,
+		},
+		{
+			name:  "split without index",
+			value: "Providence, RI",
+			extras: map[string]interface{}{
+				"operation": "split",
+				"separator": ",",
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "upper",
+			value:       "Doe",
+			extras:      map[string]interface{}{"operation": "upper"},
+			expected:    "DOE",
+			expectedErr: false,
+		},
+		{
+			name:        "lower",
+			value:       "DOE",
+			extras:      map[string]interface{}{"operation": "lower"},
+			expected:    "doe",
+			expectedErr: false,
+		},
+		{
+			name:        "title",
+			value:       "mary-jane SMITH",
+			extras:      map[string]interface{}{"operation": "title"},
+			expected:    "Mary-Jane Smith",
+			expectedErr: false,
+		},
+		{
+			name:        "trim whitespace",
+			value:       "  Doe \t",
+			extras:      map[string]interface{}{"operation": "trim"},
+			expected:    "Doe",
+			expectedErr: false,
+		},
+		{
+			name:        "trim cutset",
+			value:       "--Doe--",
+			extras:      map[string]interface{}{"operation": "trim", "cutset": "-"},
+			expected:    "Doe",
+			expectedErr: false,
+		},
+		{
+			name:  "substring",
+			value: "02860-1234",
+			extras: map[string]interface{}{
+				"operation": "substring",
+				"start":     0,
+				"length":    5.0,
+			},
+			expected:    "02860",
+			expectedErr: false,
+		},
+		{
+			name:  "substring to end",
+			value: "02860-1234",
+			extras: map[string]interface{}{
+				"operation": "substring",
+				"start":     6,
+			},
+			expected:    "1234",
+			expectedErr: false,
+		},
+		{
+			name:  "substring past end",
+			value: "028",
+			extras: map[string]interface{}{
+				"operation": "substring",
+				"start":     1,
+				"length":    10,
+			},
+			expected:    "28",
+			expectedErr: false,
+		},
+		{
+			name:  "substring with negative start",
+			value: "028",
+			extras: map[string]interface{}{
+				"operation": "substring",
+				"start":     -1,
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "normalize space",
+			value:       "  123   Havoc\n\tWay ",
+			extras:      map[string]interface{}{"operation": "normalize_space"},
+			expected:    "123 Havoc Way",
+			expectedErr: false,
+		},
+		{
+			name:        "numeric value",
+			value:       12482,
+			extras:      map[string]interface{}{"operation": "substring", "start": 0, "length": 3},
+			expected:    "124",
+			expectedErr: false,
+		},
+		{
+			name:        "unsupported operation",
+			value:       "Doe",
+			extras:      map[string]interface{}{"operation": "reverse"},
+			expectedErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformation := models.Transformation{
+				Type: "string",
+				Params: models.Params{
+					Fields: []string{"Value"},
+					Extras: test.extras,
+				},
+			}
+			actual, err := stringTransformation(map[string]interface{}{"Value": test.value}, transformation)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, actual)
+			}
+		})
+	}
+}
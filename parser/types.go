@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"havocai-assignment/internal/fieldpath"
+	"havocai-assignment/internal/maputil"
+	"havocai-assignment/models"
+	"regexp"
+	"strconv"
+)
+
+// valueParser turns the text of elements and attributes into values, using the type hint for
+// the name when there is one and the inference mode otherwise.
+type valueParser struct {
+	infer func(text string) interface{}
+	hints map[string]string
+	dates dateParser
+}
+
+// newValueParser creates a valueParser for the config's types, reading values with the date hint
+// using the config's dates.
+func newValueParser(types models.Types, dates map[string]interface{}) (*valueParser, error) {
+	datesParser, err := newCastDateParser(dates)
+	if err != nil {
+		return nil, fmt.Errorf("dates: %w", err)
+	}
+	p := &valueParser{hints: types.Hints, dates: datesParser}
+	switch types.Inference {
+	case "", "auto":
+		p.infer = parseValue
+	case "strings-only":
+		p.infer = func(text string) interface{} { return text }
+	case "strict":
+		p.infer = parseStrictValue
+	default:
+		return nil, fmt.Errorf("unsupported type inference: %v", types.Inference)
+	}
+	return p, nil
+}
+
+// mappingHints returns the config's type hints along with a string hint for every element and
+// attribute that a mapping with the string output_type reads. Their text is then kept exactly as
+// it is written, so an ID such as "12345678901234567890" or an amount such as "1.50" is not
+// changed by being parsed as a number first. A hint given in the config is kept.
+func mappingHints(cfg *models.Config) map[string]string {
+	names := mappedNames(cfg.Mappings, "string")
+	if len(names) == 0 {
+		return cfg.Types.Hints
+	}
+
+	hints := make(map[string]string, len(cfg.Types.Hints)+len(names))
+	for name, hint := range cfg.Types.Hints {
+		hints[name] = hint
+	}
+	for _, name := range names {
+		if _, ok := hints[name]; !ok {
+			hints[name] = "string"
+		}
+	}
+	return hints
+}
+
+// mappedNames returns the names of the elements and attributes that the mappings with the given
+// output_type read, from their input field and their coalesce fields. A bare name can be found
+// as an element or as an attribute, so both names are returned for it.
+func mappedNames(mappings map[string]models.Mapping, outputType string) []string {
+	var names []string
+	for _, xmlField := range maputil.SortedKeys(mappings) {
+		mapping := mappings[xmlField]
+		if mapping.OutputType != outputType {
+			continue
+		}
+		for _, field := range append([]string{xmlField}, mapping.Coalesce...) {
+			if !isPath(field) {
+				names = append(names, field, attrPrefix+field)
+				continue
+			}
+			segments, err := fieldpath.Parse(field)
+			if err != nil {
+				continue
+			}
+			names = append(names, segments[len(segments)-1].Name)
+		}
+	}
+	return names
+}
+
+// parse returns the value of the text of the element or attribute with the given name.
+// Attribute names start with "@".
+func (p *valueParser) parse(name string, text string) (interface{}, error) {
+	hint, ok := p.hints[name]
+	if !ok {
+		return p.infer(text), nil
+	}
+
+	val, ok, err := convertValue(text, hint, p.dates)
+	if err != nil {
+		return nil, fmt.Errorf("type hint for %v: %w", name, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v %q is not %v", ErrTypeHint, name, text, hint)
+	}
+	return val, nil
+}
+
+var (
+	strictIntPattern   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	strictFloatPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)\.[0-9]+$`)
+)
+
+// parseStrictValue only converts text that can be written back unchanged: plain integers and
+// decimals, which may be negative but have no leading zeros, plus sign or exponent, and the
+// literals true and false.
+// Everything else, such as ZIP codes, MRNs and phone numbers, is kept as a string.
+func parseStrictValue(val string) interface{} {
+	switch {
+	case strictIntPattern.MatchString(val):
+		if intVal, err := strconv.Atoi(val); err == nil {
+			return intVal
+		}
+	case strictFloatPattern.MatchString(val):
+		if floatVal, err := strconv.ParseFloat(val, 64); err == nil {
+			return floatVal
+		}
+	case val == "true":
+		return true
+	case val == "false":
+		return false
+	}
+	return val
+}
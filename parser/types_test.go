@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"havocai-assignment/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStrictValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		val      string
+		expected interface{}
+	}{
+		{name: "integer", val: "12345", expected: 12345},
+		{name: "negative integer", val: "-42", expected: -42},
+		{name: "zero", val: "0", expected: 0},
+		{name: "leading zero", val: "02860", expected: "02860"},
+		{name: "plus sign", val: "+15555551234", expected: "+15555551234"},
+		{name: "decimal", val: "72.50", expected: 72.5},
+		{name: "decimal below one", val: "0.5", expected: 0.5},
+		{name: "exponent", val: "1e5", expected: "1e5"},
+		{name: "integer too large", val: "99999999999999999999", expected: "99999999999999999999"},
+		{name: "true", val: "true", expected: true},
+		{name: "false", val: "false", expected: false},
+		{name: "short bool", val: "T", expected: "T"},
+		{name: "one is not bool", val: "1", expected: 1},
+		{name: "text", val: "John", expected: "John"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, parseStrictValue(test.val))
+		})
+	}
+}
+
+func TestValueParser(t *testing.T) {
+	values, err := newValueParser(models.Types{
+		Inference: "strict",
+		Hints:     map[string]string{"Zip": "int", "@Code": "string", "Visit": "date"},
+	}, nil)
+	require.NoError(t, err)
+
+	val, err := values.parse("Zip", "02860")
+	require.NoError(t, err)
+	require.Equal(t, 2860, val)
+
+	val, err = values.parse("@Code", "123")
+	require.NoError(t, err)
+	require.Equal(t, "123", val)
+
+	val, err = values.parse("Code", "123")
+	require.NoError(t, err)
+	require.Equal(t, 123, val)
+
+	_, err = values.parse("Visit", "yesterday")
+	require.ErrorIs(t, err, ErrTypeHint)
+	require.EqualError(t, err, `value does not match type hint: Visit "yesterday" is not date`)
+
+	values, err = newValueParser(models.Types{Hints: map[string]string{"Zip": "zip"}}, nil)
+	require.NoError(t, err)
+	_, err = values.parse("Zip", "02860")
+	require.EqualError(t, err, "type hint for Zip: unsupported type: zip")
+}
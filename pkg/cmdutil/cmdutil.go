@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func FatalError(msg string, err error) {
@@ -12,10 +13,39 @@ func FatalError(msg string, err error) {
 	os.Exit(1)
 }
 
-func ValidateFlags() (string, string, string) {
+// Flags holds the command line flags, with file paths made absolute.
+type Flags struct {
+	XMLPath    string
+	ConfigPath string
+	// OutputPath is empty when no -output flag was given
+	OutputPath string
+	// Now is the value of the -now flag, empty when it was not given
+	Now string
+	// Overlays are the config files given with -overlay, in the order they were given
+	Overlays []string
+	// Params are the values given with -set, by name
+	Params map[string]string
+}
+
+func ValidateFlags() Flags {
 	xmlFilePath := flag.String("xml", "", "path to XML input file")
 	configFilePath := flag.String("config", "", "path to config file")
 	outputFilePath := flag.String("output", "", "Optional: path to output JSON file")
+	now := flag.String("now", "", "Optional: time to use as the current time, as an RFC3339 timestamp or YYYY-MM-DD date. Overrides reference_time in the config")
+	var overlays []string
+	flag.Func("overlay", "Optional: path to a config file to merge on top of the config, can be repeated", func(path string) error {
+		overlays = append(overlays, path)
+		return nil
+	})
+	params := make(map[string]string)
+	flag.Func("set", "Optional: value for a ${NAME} placeholder in the config, as NAME=value, can be repeated", func(param string) error {
+		name, val, ok := strings.Cut(param, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("must be NAME=value")
+		}
+		params[name] = val
+		return nil
+	})
 
 	flag.Parse()
 
@@ -43,5 +73,59 @@ func ValidateFlags() (string, string, string) {
 		}
 	}
 
-	return absXMLPath, absConfigPath, absOutputPath
+	for i, overlay := range overlays {
+		overlays[i], err = filepath.Abs(filepath.Clean(overlay))
+		if err != nil {
+			FatalError("error resolving overlay file path: %+v\n", err)
+		}
+	}
+
+	return Flags{
+		XMLPath:    absXMLPath,
+		ConfigPath: absConfigPath,
+		OutputPath: absOutputPath,
+		Now:        *now,
+		Overlays:   overlays,
+		Params:     params,
+	}
+}
+
+// MigrateFlags holds the flags of the migrate command, with file paths made absolute.
+type MigrateFlags struct {
+	ConfigPath string
+	// OutputPath is empty when no -output flag was given
+	OutputPath string
+}
+
+// ValidateMigrateFlags parses the flags that follow the migrate command.
+func ValidateMigrateFlags(args []string) MigrateFlags {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configFilePath := flags.String("config", "", "path to the config file to migrate")
+	outputFilePath := flags.String("output", "", "Optional: path to write the migrated config to, which can be the config file itself. Defaults to stdout")
+
+	flags.Parse(args)
+
+	if *configFilePath == "" {
+		fmt.Fprintf(os.Stderr, "The -config flag is required\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	absConfigPath, err := filepath.Abs(filepath.Clean(*configFilePath))
+	if err != nil {
+		FatalError("error resolving config file path: %+v\n", err)
+	}
+
+	var absOutputPath string
+	if *outputFilePath != "" {
+		absOutputPath, err = filepath.Abs(filepath.Clean(*outputFilePath))
+		if err != nil {
+			FatalError("error resolving output file path: %+v\n", err)
+		}
+	}
+
+	return MigrateFlags{
+		ConfigPath: absConfigPath,
+		OutputPath: absOutputPath,
+	}
 }
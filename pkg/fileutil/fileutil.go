@@ -26,17 +26,11 @@ func GetOutputPath() (string, error) {
 	return outputFilePath, nil
 }
 
-func WriteToFile(filepath string, data []byte) error {
+// CreateFile creates the output file so that output can be written to it as it is produced.
+func CreateFile(filepath string) (*os.File, error) {
 	file, err := os.Create(filepath)
 	if err != nil {
-		return fmt.Errorf("error creating output file: %w", err)
+		return nil, fmt.Errorf("error creating output file: %w", err)
 	}
-
-	defer file.Close()
-
-	_, err = file.Write(data)
-	if err != nil {
-		return fmt.Errorf("error writing data to file: %w", err)
-	}
-	return nil
+	return file, nil
 }
@@ -10,12 +10,18 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// referenceTime pins CurrentTime to the date the expected outputs were produced, so ages in the
+// expected outputs stay correct.
+const referenceTime = "2025-01-29"
+
 func TestEndToEnd(t *testing.T) {
 	tests := []struct {
 		name             string
 		configPath       string
 		inputXMLPath     string
 		expectedJSONPath string
+		// args are passed to main.go after the default flags
+		args []string
 	}{
 		{
 			name:             "provided input and output",
@@ -23,6 +29,18 @@ func TestEndToEnd(t *testing.T) {
 			inputXMLPath:     "../testdata/provided/input.xml",
 			expectedJSONPath: "../testdata/provided/output.json",
 		},
+		{
+			name:             "yaml config",
+			configPath:       "../testdata/basicpatient/config.yaml",
+			inputXMLPath:     "../testdata/provided/input.xml",
+			expectedJSONPath: "../testdata/provided/output.json",
+		},
+		{
+			name:             "toml config",
+			configPath:       "../testdata/basicpatient/config.toml",
+			inputXMLPath:     "../testdata/provided/input.xml",
+			expectedJSONPath: "../testdata/provided/output.json",
+		},
 		{
 			name:             "valid single patient",
 			configPath:       "../testdata/basicpatient/config.json",
@@ -47,6 +65,56 @@ func TestEndToEnd(t *testing.T) {
 			inputXMLPath:     "../testdata/inputchanges/nested_fields.xml",
 			expectedJSONPath: "../testdata/inputchanges/nested_fields.json",
 		},
+		{
+			name:             "path expressions",
+			configPath:       "../testdata/paths/config.json",
+			inputXMLPath:     "../testdata/inputchanges/nested_fields.xml",
+			expectedJSONPath: "../testdata/inputchanges/nested_fields.json",
+		},
+		{
+			name:             "namespaced input",
+			configPath:       "../testdata/namespaces/config.json",
+			inputXMLPath:     "../testdata/namespaces/input.xml",
+			expectedJSONPath: "../testdata/namespaces/output.json",
+		},
+		{
+			name:             "lookup tables",
+			configPath:       "../testdata/lookup/config.json",
+			inputXMLPath:     "../testdata/lookup/input.xml",
+			expectedJSONPath: "../testdata/lookup/output.json",
+		},
+		{
+			name:             "version 1 config",
+			configPath:       "../testdata/migrate/v1_config.json",
+			inputXMLPath:     "../testdata/basicpatient/single_patient.xml",
+			expectedJSONPath: "../testdata/basicpatient/single_patient.json",
+		},
+		{
+			name:             "version 2 config",
+			configPath:       "../testdata/migrate/v2_config.yaml",
+			inputXMLPath:     "../testdata/basicpatient/single_patient.xml",
+			expectedJSONPath: "../testdata/basicpatient/single_patient.json",
+		},
+		{
+			name:             "config composition",
+			configPath:       "../testdata/composition/config.yaml",
+			inputXMLPath:     "../testdata/composition/input.xml",
+			expectedJSONPath: "../testdata/composition/output.json",
+		},
+		{
+			name:             "config composition with overlay",
+			configPath:       "../testdata/composition/config.yaml",
+			inputXMLPath:     "../testdata/composition/input.xml",
+			expectedJSONPath: "../testdata/composition/output_months.json",
+			args:             []string{"-overlay", "../testdata/composition/overlay_months.yaml"},
+		},
+		{
+			name:             "config params",
+			configPath:       "../testdata/params/config.yaml",
+			inputXMLPath:     "../testdata/lookup/input.xml",
+			expectedJSONPath: "../testdata/params/output.json",
+			args:             []string{"-set", "SITE_CODE=RI-01"},
+		},
 	}
 
 	for _, test := range tests {
@@ -56,7 +124,8 @@ func TestEndToEnd(t *testing.T) {
 
 			defer os.Remove(tmpOutput.Name())
 
-			cmd := exec.Command("go", "run", "../../cmd/main.go", "-xml", test.inputXMLPath, "-config", test.configPath, "-output", tmpOutput.Name())
+			args := []string{"run", "../../cmd/main.go", "-xml", test.inputXMLPath, "-config", test.configPath, "-output", tmpOutput.Name(), "-now", referenceTime}
+			cmd := exec.Command("go", append(args, test.args...)...)
 
 			var stderr bytes.Buffer
 			cmd.Stderr = &stderr